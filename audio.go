@@ -0,0 +1,69 @@
+package vpeak
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shinshin86/vpeak/wav"
+)
+
+// Audio is speech rendered into memory by Synthesize.
+type Audio struct {
+	// Data is the complete WAV file.
+	Data          []byte
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+	Duration      time.Duration
+	// Args holds the arguments of each VOICEPEAK call made for the audio, in
+	// order, including calls answered from the Cache. Their output paths
+	// refer to temporary files that no longer exist.
+	Args [][]string
+}
+
+// Synthesize renders text into memory. VOICEPEAK writes into a temporary
+// directory that is always removed before Synthesize returns. opts.Output,
+// opts.Silent, opts.Subtitles and opts.SplitSections are ignored.
+func (c *Client) Synthesize(ctx context.Context, text string, opts Options) (*Audio, error) {
+	recorder := &callRecorder{}
+	client := *c
+	client.recorder = recorder
+
+	var buf bytes.Buffer
+	if err := client.SynthesizeToContext(ctx, &buf, text, opts); err != nil {
+		return nil, err
+	}
+
+	clip, err := wav.Parse(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("read rendered audio: %w", err)
+	}
+
+	return &Audio{
+		Data:          buf.Bytes(),
+		SampleRate:    int(clip.Format.SampleRate),
+		Channels:      int(clip.Format.Channels),
+		BitsPerSample: int(clip.Format.BitsPerSample),
+		Duration:      clip.Duration(),
+		Args:          recorder.calls,
+	}, nil
+}
+
+// callRecorder collects the arguments of VOICEPEAK calls. A nil recorder
+// records nothing.
+type callRecorder struct {
+	mu    sync.Mutex
+	calls [][]string
+}
+
+func (r *callRecorder) record(args []string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, append([]string(nil), args...))
+}
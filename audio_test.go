@@ -0,0 +1,51 @@
+package vpeak
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClientSynthesize(t *testing.T) {
+	data := testWAV(4000)
+	engine := &fakeEngine{audio: data}
+	client := &Client{Engine: engine}
+
+	audio, err := client.Synthesize(context.Background(), "こんにちは。さようなら。", Options{Narrator: "f1", MaxChunkLength: 6})
+	if err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+
+	if audio.SampleRate != 8000 || audio.Channels != 1 || audio.BitsPerSample != 16 {
+		t.Fatalf("format = %d Hz, %d channels, %d bits", audio.SampleRate, audio.Channels, audio.BitsPerSample)
+	}
+	if want := time.Second; audio.Duration != want {
+		t.Fatalf("Duration = %v, want %v", audio.Duration, want)
+	}
+	if !bytes.HasPrefix(audio.Data, []byte("RIFF")) {
+		t.Fatalf("Data does not hold a WAV file")
+	}
+	if !reflect.DeepEqual(audio.Args, engine.calls) {
+		t.Fatalf("Args = %#v, want %#v", audio.Args, engine.calls)
+	}
+	if len(audio.Args) != 2 {
+		t.Fatalf("Args = %#v, want one call per sentence", audio.Args)
+	}
+	if _, err := os.Stat(filepath.Dir(audio.Args[0][1])); !os.IsNotExist(err) {
+		t.Fatalf("temporary files not removed")
+	}
+}
+
+func TestClientSynthesizeError(t *testing.T) {
+	errBoom := errors.New("boom")
+	client := &Client{Engine: &fakeEngine{err: errBoom}}
+
+	if _, err := client.Synthesize(context.Background(), "hi", Options{}); !errors.Is(err, errBoom) {
+		t.Fatalf("Synthesize() error = %v, want %v", err, errBoom)
+	}
+}
@@ -0,0 +1,328 @@
+package vpeak
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchResult reports the outcome of every file of a batch, in input order.
+type BatchResult struct {
+	Files []FileResult
+}
+
+// FileResult is the outcome of rendering one input file.
+type FileResult struct {
+	Input  string
+	Output string
+	// Duration is how long the file took to render and play.
+	Duration time.Duration
+	// Skipped is set if the file was already rendered by an earlier run.
+	Skipped bool
+	// Err is nil if the file was rendered successfully. Files that were not
+	// started because the batch was canceled carry the context error.
+	Err error
+
+	// name is the key of the file in the manifest.
+	name string
+}
+
+// Failed returns the files whose rendering failed.
+func (r *BatchResult) Failed() []FileResult {
+	var failed []FileResult
+	for _, file := range r.Files {
+		if file.Err != nil {
+			failed = append(failed, file)
+		}
+	}
+	return failed
+}
+
+// Succeeded returns the number of files rendered successfully, including
+// skipped files.
+func (r *BatchResult) Succeeded() int {
+	return len(r.Files) - len(r.Failed())
+}
+
+// Skipped returns the number of files skipped as already rendered.
+func (r *BatchResult) Skipped() int {
+	skipped := 0
+	for _, file := range r.Files {
+		if file.Skipped {
+			skipped++
+		}
+	}
+	return skipped
+}
+
+// ProcessTextFiles processes text files in a directory and generates audio files
+func (c *Client) ProcessTextFiles(dir string, opts Options) (*BatchResult, error) {
+	return c.ProcessTextFilesContext(context.Background(), dir, opts)
+}
+
+// ProcessTextFilesContext renders the text files in dir into .wav files of
+// the same name, in dir or in the directory opts.Output. Up to opts.Workers
+// files are rendered at the same time. A file that fails does not stop the
+// batch; its error is recorded in the result. When ctx is done no new files
+// are started and ctx.Err() is returned along with the result.
+//
+// Files are selected by opts.Extensions, opts.Include and opts.Exclude. With
+// opts.Recursive, subdirectories are processed too and their layout is
+// mirrored in the output directory.
+//
+// Every rendered file is recorded in ManifestName in the output directory,
+// which is dir itself when opts.Output is empty. Like the outputs, a relative
+// output directory is resolved against c.Dir. With opts.Resume, files
+// whose contents, options and output are unchanged since they were recorded
+// are skipped, unless opts.Force is set. Without opts.Resume an unreadable
+// manifest is replaced.
+func (c *Client) ProcessTextFilesContext(ctx context.Context, dir string, opts Options) (*BatchResult, error) {
+	opts, err := resolveProfile(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts, err = c.validate(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	outputDir := dir
+	if opts.Output != "" {
+		outputDir = opts.Output
+	}
+
+	files, err := collectFiles(dir, outputDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	manifestPath := c.outputPath(Options{Output: filepath.Join(outputDir, ManifestName)})
+	manifest, err := ReadManifest(manifestPath)
+	if err != nil {
+		if opts.Resume {
+			return nil, err
+		}
+		manifest = &Manifest{Files: map[string]ManifestEntry{}}
+	}
+
+	result := &BatchResult{Files: files}
+	recorder := &manifestRecorder{path: manifestPath, manifest: manifest}
+	c.processFiles(ctx, result.Files, opts, recorder)
+	return result, ctx.Err()
+}
+
+// collectFiles lists the files in dir selected by opts, in lexical order,
+// with their outputs under outputDir.
+func collectFiles(dir, outputDir string, opts Options) ([]FileResult, error) {
+	for _, pattern := range append(append([]string(nil), opts.Include...), opts.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	extensions := opts.Extensions
+	if len(extensions) == 0 {
+		extensions = []string{".txt"}
+		if opts.Format == FormatMarkdown {
+			extensions = []string{".md", ".markdown"}
+		}
+	}
+
+	// WalkDir does not follow a symlinked root, so it walks the target and
+	// the inputs are named under dir.
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory: %v", err)
+	}
+
+	var files []FileResult
+	err = filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("error reading directory: %v", err)
+		}
+		if filePath == root {
+			if !entry.IsDir() {
+				return fmt.Errorf("error reading directory: %s is not a directory", dir)
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		if entry.IsDir() {
+			if !opts.Recursive || matchAny(opts.Exclude, name) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !hasExtension(name, extensions) || matchAny(opts.Exclude, name) {
+			return nil
+		}
+		if len(opts.Include) > 0 && !matchAny(opts.Include, name) {
+			return nil
+		}
+
+		files = append(files, FileResult{
+			Input:  filepath.Join(dir, rel),
+			Output: filepath.Join(outputDir, convertWavExt(rel)),
+			name:   name,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// a.txt and a.md both render to a.wav.
+	inputs := make(map[string]string, len(files))
+	for _, file := range files {
+		if other, ok := inputs[file.Output]; ok {
+			return nil, fmt.Errorf("%s and %s would both be rendered to %s", other, file.Input, file.Output)
+		}
+		inputs[file.Output] = file.Input
+	}
+	return files, nil
+}
+
+// matchAny reports whether name, a slash-separated path relative to the
+// input directory, matches one of patterns. Patterns without a slash are
+// matched against the base name.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		target := name
+		if !strings.Contains(pattern, "/") {
+			target = path.Base(name)
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func hasExtension(name string, extensions []string) bool {
+	ext := path.Ext(name)
+	for _, want := range extensions {
+		if !strings.HasPrefix(want, ".") {
+			want = "." + want
+		}
+		if strings.EqualFold(ext, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// processFiles renders files with a pool of opts.Workers workers, filling in
+// each file's duration and error. Files are rendered concurrently but played
+// one at a time.
+func (c *Client) processFiles(ctx context.Context, files []FileResult, opts Options, recorder *manifestRecorder) {
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var playing sync.Mutex
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				c.processFile(ctx, &files[index], opts, recorder, &playing)
+			}
+		}()
+	}
+
+	for i := range files {
+		if ctx.Err() != nil {
+			files[i].Err = ctx.Err()
+			continue
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			files[i].Err = ctx.Err()
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+func (c *Client) processFile(ctx context.Context, file *FileResult, opts Options, recorder *manifestRecorder, playing *sync.Mutex) {
+	start := time.Now()
+	defer func() { file.Duration = time.Since(start) }()
+
+	content, err := os.ReadFile(file.Input)
+	if err != nil {
+		file.Err = fmt.Errorf("read %s: %w", file.Input, err)
+		return
+	}
+
+	hash := hashContent(content)
+	renderOpts := manifestOptions(opts)
+	output := c.outputPath(Options{Output: file.Output})
+	if opts.Resume && !opts.Force && recorder.upToDate(file.name, hash, renderOpts, output) {
+		file.Skipped = true
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+		file.Err = fmt.Errorf("create output directory: %w", err)
+		return
+	}
+
+	fileOpts := opts
+	fileOpts.Output = file.Output
+	fileOpts.Silent = true
+	if err := c.generateSpeech(ctx, string(content), fileOpts); err != nil {
+		file.Err = err
+		return
+	}
+
+	if err := recorder.record(file.name, ManifestEntry{
+		Hash:      hash,
+		Options:   renderOpts,
+		Output:    output,
+		Completed: time.Now(),
+	}); err != nil {
+		file.Err = err
+		return
+	}
+
+	if !opts.Silent {
+		playing.Lock()
+		defer playing.Unlock()
+		file.Err = c.playBatchOutputs(ctx, string(content), fileOpts)
+	}
+}
+
+// playBatchOutputs plays the files rendered from content with opts: the
+// output itself, or each section's file with opts.SplitSections.
+func (c *Client) playBatchOutputs(ctx context.Context, content string, opts Options) error {
+	outputs := []string{opts.Output}
+	if opts.Format == FormatMarkdown && opts.SplitSections {
+		outputs = outputs[:0]
+		for n := range ParseMarkdown(content) {
+			outputs = append(outputs, sectionOutput(opts.Output, n+1))
+		}
+	}
+
+	for _, output := range outputs {
+		if err := c.PlayAudioContext(ctx, c.outputPath(Options{Output: output})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
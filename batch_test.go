@@ -0,0 +1,311 @@
+package vpeak
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// concurrencyEngine records how many calls overlap and fails calls whose text
+// is "fail".
+type concurrencyEngine struct {
+	mu      sync.Mutex
+	running int
+	max     int
+}
+
+func (e *concurrencyEngine) Run(ctx context.Context, args []string) ([]byte, error) {
+	e.mu.Lock()
+	e.running++
+	if e.running > e.max {
+		e.max = e.running
+	}
+	e.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	e.mu.Lock()
+	e.running--
+	e.mu.Unlock()
+
+	if args[len(args)-1] == "fail" {
+		return []byte("synthesis failed"), errors.New("exit status 1")
+	}
+	return nil, nil
+}
+
+// concurrencyPlayer records how many playbacks overlap.
+type concurrencyPlayer struct {
+	concurrencyEngine
+	played int
+}
+
+func (p *concurrencyPlayer) Play(ctx context.Context, path string) error {
+	p.mu.Lock()
+	p.played++
+	p.mu.Unlock()
+	_, err := p.Run(ctx, []string{path})
+	return err
+}
+
+func writeTextFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+	}
+}
+
+func TestClientProcessTextFilesWorkers(t *testing.T) {
+	dir := t.TempDir()
+	writeTextFiles(t, dir, map[string]string{
+		"a.txt":     "one",
+		"b.txt":     "fail",
+		"c.txt":     "three",
+		"d.txt":     "four",
+		"notes.md":  "skipped",
+		"e.txt.bak": "skipped",
+	})
+
+	engine := &concurrencyEngine{}
+	client := &Client{Engine: engine}
+
+	result, err := client.ProcessTextFiles(dir, Options{Silent: true, Workers: 4})
+	if err != nil {
+		t.Fatalf("ProcessTextFiles() error = %v", err)
+	}
+
+	if engine.max < 2 {
+		t.Fatalf("max concurrent calls = %d, want at least 2", engine.max)
+	}
+	if engine.max > 4 {
+		t.Fatalf("max concurrent calls = %d, want at most 4", engine.max)
+	}
+
+	wantInputs := []string{"a.txt", "b.txt", "c.txt", "d.txt"}
+	if len(result.Files) != len(wantInputs) {
+		t.Fatalf("Files = %#v, want %d files", result.Files, len(wantInputs))
+	}
+	for i, file := range result.Files {
+		if file.Input != filepath.Join(dir, wantInputs[i]) {
+			t.Fatalf("Files[%d].Input = %q, want %q", i, file.Input, wantInputs[i])
+		}
+		if want := filepath.Join(dir, convertWavExt(wantInputs[i])); file.Output != want {
+			t.Fatalf("Files[%d].Output = %q, want %q", i, file.Output, want)
+		}
+		if file.Duration <= 0 {
+			t.Fatalf("Files[%d].Duration = %v, want > 0", i, file.Duration)
+		}
+	}
+
+	failed := result.Failed()
+	if len(failed) != 1 || failed[0].Input != filepath.Join(dir, "b.txt") {
+		t.Fatalf("Failed() = %#v, want b.txt", failed)
+	}
+	if result.Succeeded() != 3 {
+		t.Fatalf("Succeeded() = %d, want 3", result.Succeeded())
+	}
+}
+
+func TestClientProcessTextFilesPlaysOneAtATime(t *testing.T) {
+	dir := t.TempDir()
+	writeTextFiles(t, dir, map[string]string{"a.txt": "one", "b.txt": "two", "c.txt": "three", "d.txt": "four"})
+
+	engine := &concurrencyEngine{}
+	player := &concurrencyPlayer{}
+	client := &Client{Engine: engine, Player: player}
+
+	if _, err := client.ProcessTextFiles(dir, Options{Workers: 4}); err != nil {
+		t.Fatalf("ProcessTextFiles() error = %v", err)
+	}
+
+	if engine.max < 2 {
+		t.Fatalf("max concurrent calls = %d, want at least 2", engine.max)
+	}
+	if player.played != 4 || player.max != 1 {
+		t.Fatalf("played = %d with %d at a time, want 4 with 1 at a time", player.played, player.max)
+	}
+}
+
+func TestClientProcessTextFilesPlaysSplitSections(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(t.TempDir(), "out")
+	writeTextFiles(t, dir, map[string]string{"a.md": "# One\n\nfirst\n\n# Two\n\nsecond\n"})
+
+	player := &fakePlayer{}
+	client := &Client{Engine: &fakeEngine{audio: testWAV(10)}, Player: player}
+	result, err := client.ProcessTextFiles(dir, Options{Output: out, Format: FormatMarkdown, SplitSections: true})
+	if err != nil {
+		t.Fatalf("ProcessTextFiles() error = %v", err)
+	}
+	if failed := result.Failed(); len(failed) != 0 {
+		t.Fatalf("Failed() = %v, want none", failed[0].Err)
+	}
+
+	want := []string{filepath.Join(out, "a-01.wav"), filepath.Join(out, "a-02.wav")}
+	if !reflect.DeepEqual(player.played, want) {
+		t.Fatalf("played = %q, want %q", player.played, want)
+	}
+	for _, path := range want {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("played file missing: %v", err)
+		}
+	}
+}
+
+func TestClientProcessTextFilesSequentialByDefault(t *testing.T) {
+	dir := t.TempDir()
+	writeTextFiles(t, dir, map[string]string{"a.txt": "one", "b.txt": "two", "c.txt": "three"})
+
+	engine := &concurrencyEngine{}
+	client := &Client{Engine: engine}
+
+	out := t.TempDir()
+	result, err := client.ProcessTextFiles(dir, Options{Silent: true, Output: out})
+	if err != nil {
+		t.Fatalf("ProcessTextFiles() error = %v", err)
+	}
+	if engine.max != 1 {
+		t.Fatalf("max concurrent calls = %d, want 1", engine.max)
+	}
+	if got, want := result.Files[0].Output, filepath.Join(out, "a.wav"); got != want {
+		t.Fatalf("Files[0].Output = %q, want %q", got, want)
+	}
+}
+
+func TestClientProcessTextFilesRecursive(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"part1", "part2", "drafts"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatalf("os.Mkdir() error = %v", err)
+		}
+	}
+	writeTextFiles(t, dir, map[string]string{
+		"intro.txt":          "intro",
+		"part1/ch01.txt":     "one",
+		"part1/ch02.md":      "two",
+		"part1/notes.txt":    "notes",
+		"part2/ch03.TXT":     "three",
+		"drafts/ch99.txt":    "draft",
+		"part2/cover.jpg.md": "cover",
+	})
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{
+			name: "top level only",
+			opts: Options{},
+			want: []string{"intro.txt"},
+		},
+		{
+			name: "recursive",
+			opts: Options{Recursive: true},
+			want: []string{"drafts/ch99.txt", "intro.txt", "part1/ch01.txt", "part1/notes.txt", "part2/ch03.TXT"},
+		},
+		{
+			name: "extensions",
+			opts: Options{Recursive: true, Extensions: []string{"md", ".txt"}, Exclude: []string{"drafts", "*.jpg.md"}},
+			want: []string{"intro.txt", "part1/ch01.txt", "part1/ch02.md", "part1/notes.txt", "part2/ch03.TXT"},
+		},
+		{
+			name: "include base name",
+			opts: Options{Recursive: true, Include: []string{"ch*"}, Exclude: []string{"drafts"}},
+			want: []string{"part1/ch01.txt", "part2/ch03.TXT"},
+		},
+		{
+			name: "include path",
+			opts: Options{Recursive: true, Include: []string{"part1/*"}},
+			want: []string{"part1/ch01.txt", "part1/notes.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := t.TempDir()
+			tt.opts.Output = out
+			tt.opts.Silent = true
+
+			client := &Client{Engine: &fakeEngine{audio: testWAV(10)}}
+			result, err := client.ProcessTextFiles(dir, tt.opts)
+			if err != nil {
+				t.Fatalf("ProcessTextFiles() error = %v", err)
+			}
+			if failed := result.Failed(); len(failed) != 0 {
+				t.Fatalf("Failed() = %#v, want none", failed)
+			}
+
+			var got []string
+			for _, file := range result.Files {
+				rel, _ := filepath.Rel(dir, file.Input)
+				got = append(got, filepath.ToSlash(rel))
+
+				if want := filepath.Join(out, convertWavExt(rel)); file.Output != want {
+					t.Fatalf("Output = %q, want %q", file.Output, want)
+				}
+				if _, err := os.Stat(file.Output); err != nil {
+					t.Fatalf("output not written: %v", err)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("files = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientProcessTextFilesSymlinkedDirectory(t *testing.T) {
+	target := t.TempDir()
+	if err := os.Mkdir(filepath.Join(target, "part1"), 0o755); err != nil {
+		t.Fatalf("os.Mkdir() error = %v", err)
+	}
+	writeTextFiles(t, target, map[string]string{"a.txt": "one", "part1/b.txt": "two"})
+	dir := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(target, dir); err != nil {
+		t.Skipf("os.Symlink() error = %v", err)
+	}
+
+	out := t.TempDir()
+	client := &Client{Engine: &fakeEngine{audio: testWAV(10)}}
+	result, err := client.ProcessTextFiles(dir, Options{Output: out, Silent: true, Recursive: true})
+	if err != nil {
+		t.Fatalf("ProcessTextFiles() error = %v", err)
+	}
+	if result.Succeeded() != 2 {
+		t.Fatalf("result = %#v, want 2 succeeded", result.Files)
+	}
+	if want := filepath.Join(dir, "part1", "b.txt"); result.Files[1].Input != want {
+		t.Fatalf("Input = %q, want %q", result.Files[1].Input, want)
+	}
+}
+
+func TestClientProcessTextFilesInvalidPattern(t *testing.T) {
+	client := &Client{Engine: &fakeEngine{}}
+	if _, err := client.ProcessTextFiles(t.TempDir(), Options{Include: []string{"["}}); err == nil {
+		t.Fatal("ProcessTextFiles() error = nil, want invalid pattern error")
+	}
+}
+
+func TestClientProcessTextFilesDuplicateOutput(t *testing.T) {
+	dir := t.TempDir()
+	writeTextFiles(t, dir, map[string]string{"a.txt": "one", "a.md": "two"})
+
+	engine := &fakeEngine{}
+	client := &Client{Engine: engine}
+	_, err := client.ProcessTextFiles(dir, Options{Silent: true, Extensions: []string{".txt", ".md"}})
+	if err == nil || !strings.Contains(err.Error(), "a.wav") {
+		t.Fatalf("ProcessTextFiles() error = %v, want duplicate a.wav error", err)
+	}
+	if len(engine.calls) != 0 {
+		t.Fatalf("engine calls = %d, want 0", len(engine.calls))
+	}
+}
@@ -0,0 +1,257 @@
+package vpeak
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultCacheMaxSize is the default size limit of a Cache in bytes.
+	DefaultCacheMaxSize = 1 << 30
+	// DefaultCacheMaxAge is the default time after which unused entries expire.
+	DefaultCacheMaxAge = 30 * 24 * time.Hour
+
+	cacheExt = ".wav"
+	// cacheKeyVersion changes whenever the key derivation changes.
+	cacheKeyVersion = "vpeak-cache-v1"
+)
+
+// Cache stores rendered audio on disk, keyed by the VOICEPEAK arguments that
+// produced it. Entries are evicted least recently used first once the cache
+// grows beyond MaxSize, and when unused for longer than MaxAge.
+type Cache struct {
+	Dir string
+	// MaxSize is the total size limit in bytes. Zero means no limit.
+	MaxSize int64
+	// MaxAge is how long an entry may go unused. Zero means no limit.
+	MaxAge time.Duration
+	// DictionaryPath is the dictionary whose contents are part of every key,
+	// so that editing the dictionary invalidates cached audio. If empty,
+	// DefaultDictionaryPath is used.
+	DictionaryPath string
+}
+
+// CacheStats summarizes the contents of a Cache.
+type CacheStats struct {
+	Dir     string
+	Entries int
+	Size    int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// DefaultCacheDir returns the vpeak directory under the user cache directory,
+// e.g. ~/.cache/vpeak on Linux or ~/Library/Caches/vpeak on macOS.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve cache directory: %w", err)
+	}
+	return filepath.Join(dir, "vpeak"), nil
+}
+
+// NewCache returns a cache in dir with the default limits.
+func NewCache(dir string) *Cache {
+	return &Cache{Dir: dir, MaxSize: DefaultCacheMaxSize, MaxAge: DefaultCacheMaxAge}
+}
+
+// Key derives the cache key of a VOICEPEAK call from the executable, its
+// arguments (without the output path) and the current dictionary.
+func (c *Cache) Key(executable string, args []string) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00", cacheKeyVersion, executable)
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-o" {
+			i++
+			continue
+		}
+		fmt.Fprintf(hash, "%s\x00", args[i])
+	}
+	fmt.Fprintf(hash, "dictionary\x00%s", c.dictionaryFingerprint())
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (c *Cache) dictionaryFingerprint() string {
+	path := c.DictionaryPath
+	if path == "" {
+		var err error
+		if path, err = DefaultDictionaryPath(); err != nil {
+			return "none"
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "none"
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Get copies the entry for key to dst and reports whether it existed. An
+// entry removed by a concurrent Prune is reported as missing.
+func (c *Cache) Get(key, dst string) (bool, error) {
+	path := c.path(key)
+	if err := copyFile(path, dst); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("read cache entry: %w", err)
+	}
+
+	// Touch the entry so that eviction is least recently used.
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return true, nil
+}
+
+// Put stores a copy of src under key and evicts entries beyond the limits.
+func (c *Cache) Put(key, src string) error {
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return fmt.Errorf("create cache directory: %w", err)
+	}
+
+	tempFile, err := os.CreateTemp(c.Dir, key+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create cache entry: %w", err)
+	}
+	tempPath := tempFile.Name()
+	defer os.Remove(tempPath)
+
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("create cache entry: %w", err)
+	}
+	if err := copyFile(src, tempPath); err != nil {
+		return fmt.Errorf("write cache entry: %w", err)
+	}
+	if err := os.Rename(tempPath, c.path(key)); err != nil {
+		return fmt.Errorf("write cache entry: %w", err)
+	}
+
+	return c.Prune()
+}
+
+// Prune removes entries unused for longer than MaxAge, then the least
+// recently used entries until the cache fits in MaxSize.
+func (c *Cache) Prune() error {
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	var kept []os.FileInfo
+	for _, entry := range entries {
+		if c.MaxAge > 0 && time.Since(entry.ModTime()) > c.MaxAge {
+			if err := os.Remove(filepath.Join(c.Dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		size += entry.Size()
+		kept = append(kept, entry)
+	}
+
+	sort.Slice(kept, func(i, j int) bool {
+		return kept[i].ModTime().Before(kept[j].ModTime())
+	})
+	for _, entry := range kept {
+		if c.MaxSize <= 0 || size <= c.MaxSize {
+			break
+		}
+		if err := os.Remove(filepath.Join(c.Dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		size -= entry.Size()
+	}
+
+	return nil
+}
+
+// Stats reports the number, total size and age range of cached entries.
+func (c *Cache) Stats() (CacheStats, error) {
+	stats := CacheStats{Dir: c.Dir}
+	entries, err := c.entries()
+	if err != nil {
+		return stats, err
+	}
+
+	for _, entry := range entries {
+		stats.Entries++
+		stats.Size += entry.Size()
+		if stats.Oldest.IsZero() || entry.ModTime().Before(stats.Oldest) {
+			stats.Oldest = entry.ModTime()
+		}
+		if entry.ModTime().After(stats.Newest) {
+			stats.Newest = entry.ModTime()
+		}
+	}
+	return stats, nil
+}
+
+// Clear removes every cached entry.
+func (c *Cache) Clear() error {
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := os.Remove(filepath.Join(c.Dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cache) entries() ([]os.FileInfo, error) {
+	dirEntries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read cache directory: %w", err)
+	}
+
+	var entries []os.FileInfo
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), cacheExt) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, info)
+	}
+	return entries, nil
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, key+cacheExt)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
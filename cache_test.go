@@ -0,0 +1,137 @@
+package vpeak
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheKeyIgnoresOutputAndTracksDictionary(t *testing.T) {
+	dir := t.TempDir()
+	dictPath := filepath.Join(dir, "dic.json")
+	cache := &Cache{Dir: filepath.Join(dir, "cache"), DictionaryPath: dictPath}
+
+	a := cache.Key("voicepeak", []string{"-o", "a.wav", "-s", "hi"})
+	b := cache.Key("voicepeak", []string{"-o", "b.wav", "-s", "hi"})
+	if a != b {
+		t.Fatalf("keys differ only by output: %s != %s", a, b)
+	}
+	if a == cache.Key("voicepeak", []string{"-o", "a.wav", "-s", "bye"}) {
+		t.Fatal("keys for different text are equal")
+	}
+
+	if err := SaveDictionary(dictPath, []DictEntry{sampleDictEntry("GitHub", "ギットハブ")}); err != nil {
+		t.Fatalf("SaveDictionary() error = %v", err)
+	}
+	if a == cache.Key("voicepeak", []string{"-s", "hi"}) {
+		t.Fatal("key did not change after editing the dictionary")
+	}
+}
+
+func TestCachePutGetClear(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewCache(filepath.Join(dir, "cache"))
+	src := filepath.Join(dir, "src.wav")
+	if err := os.WriteFile(src, []byte("audio"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	dst := filepath.Join(dir, "dst.wav")
+	if hit, err := cache.Get("k", dst); hit || err != nil {
+		t.Fatalf("Get() on empty cache = %v, %v", hit, err)
+	}
+
+	if err := cache.Put("k", src); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if hit, err := cache.Get("k", dst); !hit || err != nil {
+		t.Fatalf("Get() = %v, %v, want hit", hit, err)
+	}
+	if data, _ := os.ReadFile(dst); !bytes.Equal(data, []byte("audio")) {
+		t.Fatalf("cached data = %q", data)
+	}
+
+	stats, err := cache.Stats()
+	if err != nil || stats.Entries != 1 || stats.Size != 5 {
+		t.Fatalf("Stats() = %+v, %v", stats, err)
+	}
+
+	if err := cache.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if stats, _ := cache.Stats(); stats.Entries != 0 {
+		t.Fatalf("Stats() after Clear = %+v", stats)
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	dir := t.TempDir()
+	cache := &Cache{Dir: dir, MaxSize: 10, MaxAge: time.Hour}
+
+	now := time.Now()
+	write := func(name string, size int, age time.Duration) {
+		path := filepath.Join(dir, name+cacheExt)
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatalf("os.Chtimes() error = %v", err)
+		}
+	}
+	write("expired", 1, 2*time.Hour)
+	write("old", 4, 30*time.Minute)
+	write("mid", 4, 20*time.Minute)
+	write("new", 4, time.Minute)
+
+	if err := cache.Prune(); err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+
+	for name, want := range map[string]bool{"expired": false, "old": false, "mid": true, "new": true} {
+		_, err := os.Stat(filepath.Join(dir, name+cacheExt))
+		if exists := err == nil; exists != want {
+			t.Errorf("%s exists = %v, want %v", name, exists, want)
+		}
+	}
+}
+
+func TestClientUsesCache(t *testing.T) {
+	dir := t.TempDir()
+	engine := &fakeEngine{audio: testWAV(10)}
+	client := &Client{Engine: engine, Cache: &Cache{Dir: filepath.Join(dir, "cache"), DictionaryPath: filepath.Join(dir, "dic.json")}}
+
+	for _, name := range []string{"first.wav", "second.wav"} {
+		output := filepath.Join(dir, name)
+		if err := client.GenerateSpeech("hi", Options{Output: output, Silent: true}); err != nil {
+			t.Fatalf("GenerateSpeech() error = %v", err)
+		}
+		if _, err := os.Stat(output); err != nil {
+			t.Fatalf("output %s missing: %v", name, err)
+		}
+	}
+
+	if len(engine.calls) != 1 {
+		t.Fatalf("engine calls = %d, want 1", len(engine.calls))
+	}
+}
+
+func TestClientIgnoresCacheErrors(t *testing.T) {
+	dir := t.TempDir()
+	// A file in place of the cache directory makes every Put fail.
+	cacheDir := filepath.Join(dir, "cache")
+	if err := os.WriteFile(cacheDir, nil, 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	engine := &fakeEngine{audio: testWAV(10)}
+	client := &Client{Engine: engine, Cache: &Cache{Dir: cacheDir, DictionaryPath: filepath.Join(dir, "dic.json")}}
+
+	output := filepath.Join(dir, "out.wav")
+	if err := client.GenerateSpeech("hi", Options{Output: output, Silent: true}); err != nil {
+		t.Fatalf("GenerateSpeech() error = %v", err)
+	}
+	if _, err := os.Stat(output); err != nil {
+		t.Fatalf("output missing: %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package vpeak
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// CatalogName is the name of the catalog file in DefaultCacheDir.
+	CatalogName = "catalog.json"
+	// DefaultCatalogMaxAge is the default time after which listings are
+	// fetched again, so that newly installed narrators show up.
+	DefaultCatalogMaxAge = 24 * time.Hour
+)
+
+// Catalog stores the narrators and emotions listed by VOICEPEAK in a file,
+// so that listing and validating them does not start VOICEPEAK every time.
+// The listings of an executable are discarded when its size or modification
+// time changes, and when they are older than MaxAge.
+type Catalog struct {
+	Path string
+	// MaxAge is how long listings are reused. Zero means no limit.
+	MaxAge time.Duration
+
+	mu sync.Mutex
+}
+
+// catalogFile is the contents of a catalog file.
+type catalogFile struct {
+	Executables map[string]*catalogEntry `json:"executables"`
+}
+
+// catalogEntry holds the listings of one VOICEPEAK executable.
+type catalogEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Created time.Time `json:"created"`
+	// Lists maps the list arguments, e.g. "--list-emotion Japanese Male 1",
+	// to the names VOICEPEAK printed.
+	Lists map[string][]string `json:"lists"`
+}
+
+// DefaultCatalogPath returns CatalogName in DefaultCacheDir.
+func DefaultCatalogPath() (string, error) {
+	dir, err := DefaultCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, CatalogName), nil
+}
+
+// NewCatalog returns a catalog stored at path with the default MaxAge.
+func NewCatalog(path string) *Catalog {
+	return &Catalog{Path: path, MaxAge: DefaultCatalogMaxAge}
+}
+
+// Clear removes the catalog file.
+func (c *Catalog) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.Remove(c.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// list returns the listing of executable for args, calling fetch and
+// storing its result if the catalog has no current one or refresh is set.
+// The catalog is best-effort: executables that cannot be inspected are not
+// cached, and a catalog that cannot be read or written only costs the fetch.
+func (c *Catalog) list(executable string, args []string, refresh bool, fetch func() ([]string, error)) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if path, err := exec.LookPath(executable); err == nil {
+		executable = path
+	}
+	info, err := os.Stat(executable)
+	if err != nil {
+		return fetch()
+	}
+
+	file, err := c.read()
+	if err != nil {
+		return fetch()
+	}
+
+	key := strings.Join(args, " ")
+	entry := file.Executables[executable]
+	if entry == nil || entry.Size != info.Size() || !entry.ModTime.Equal(info.ModTime()) ||
+		(c.MaxAge > 0 && time.Since(entry.Created) > c.MaxAge) {
+		entry = &catalogEntry{Size: info.Size(), ModTime: info.ModTime(), Created: time.Now(), Lists: map[string][]string{}}
+		file.Executables[executable] = entry
+	} else if names, ok := entry.Lists[key]; ok && !refresh {
+		return append([]string(nil), names...), nil
+	}
+
+	names, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	entry.Lists[key] = append([]string{}, names...)
+	_ = c.write(file)
+	return names, nil
+}
+
+func (c *Catalog) read() (*catalogFile, error) {
+	file := &catalogFile{}
+	data, err := os.ReadFile(c.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read catalog: %w", err)
+	}
+	if err == nil && json.Unmarshal(data, file) != nil {
+		// A corrupt catalog is rebuilt.
+		file = &catalogFile{}
+	}
+	if file.Executables == nil {
+		file.Executables = map[string]*catalogEntry{}
+	}
+	return file, nil
+}
+
+func (c *Catalog) write(file *catalogFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0o755); err != nil {
+		return err
+	}
+	tempFile, err := os.CreateTemp(filepath.Dir(c.Path), filepath.Base(c.Path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tempPath := tempFile.Name()
+	defer os.Remove(tempPath)
+
+	_, err = tempFile.Write(append(data, '\n'))
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tempPath, c.Path)
+}
@@ -0,0 +1,240 @@
+package vpeak_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shinshin86/vpeak"
+	"github.com/shinshin86/vpeak/vpeaktest"
+)
+
+func TestCatalogCachesListings(t *testing.T) {
+	fake := vpeaktest.New(t)
+	client := fake.Client()
+	client.Catalog = vpeak.NewCatalog(filepath.Join(t.TempDir(), vpeak.CatalogName))
+
+	for i := 0; i < 2; i++ {
+		narrators, err := client.ListNarrators()
+		if err != nil {
+			t.Fatalf("ListNarrators() error = %v", err)
+		}
+		if !reflect.DeepEqual(narrators, vpeaktest.DefaultNarrators) {
+			t.Fatalf("ListNarrators() = %q, want %q", narrators, vpeaktest.DefaultNarrators)
+		}
+		if _, err := client.ListEmotions("f1"); err != nil {
+			t.Fatalf("ListEmotions() error = %v", err)
+		}
+	}
+	if calls := fake.Calls(); len(calls) != 2 {
+		t.Fatalf("Calls() = %q, want each listing run once", calls)
+	}
+
+	// Replacing the executable invalidates its listings.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(fake.Path, later, later); err != nil {
+		t.Fatalf("os.Chtimes() error = %v", err)
+	}
+	if _, err := client.ListNarrators(); err != nil {
+		t.Fatalf("ListNarrators() error = %v", err)
+	}
+	if calls := fake.Calls(); len(calls) != 3 {
+		t.Fatalf("Calls() = %q, want the narrators listed again", calls)
+	}
+
+	if err := client.Catalog.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if _, err := client.ListEmotions("f1"); err != nil {
+		t.Fatalf("ListEmotions() error = %v", err)
+	}
+	if calls := fake.Calls(); len(calls) != 4 {
+		t.Fatalf("Calls() = %q, want the emotions listed again", calls)
+	}
+}
+
+func TestCatalogMaxAge(t *testing.T) {
+	fake := vpeaktest.New(t)
+	client := fake.Client()
+	client.Catalog = &vpeak.Catalog{Path: filepath.Join(t.TempDir(), vpeak.CatalogName), MaxAge: time.Nanosecond}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListNarrators(); err != nil {
+			t.Fatalf("ListNarrators() error = %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if calls := fake.Calls(); len(calls) != 2 {
+		t.Fatalf("Calls() = %q, want expired listings fetched again", calls)
+	}
+}
+
+func TestCatalogFindsNewlyInstalledNarrator(t *testing.T) {
+	fake := vpeaktest.New(t)
+	client := fake.Client()
+	client.Catalog = vpeak.NewCatalog(filepath.Join(t.TempDir(), vpeak.CatalogName))
+	if _, err := client.ListNarrators(); err != nil {
+		t.Fatalf("ListNarrators() error = %v", err)
+	}
+
+	// Installing a narrator leaves the executable unchanged.
+	fake.Update(func(c *vpeaktest.Config) {
+		c.Narrators = append(c.Narrators, "宮舞モカ")
+		c.Emotions = map[string][]string{"宮舞モカ": {"amaama", "live"}}
+	})
+	fake.Reset()
+
+	narrator, err := client.ResolveNarrator(context.Background(), "宮舞モカ")
+	if err != nil || narrator != "宮舞モカ" {
+		t.Fatalf("ResolveNarrator() = %q, %v, want the new narrator", narrator, err)
+	}
+	output := filepath.Join(t.TempDir(), "moka.wav")
+	if err := client.GenerateSpeech("こんにちは", vpeak.Options{Narrator: "宮舞", Emotion: "live", Output: output, Silent: true, Strict: true}); err != nil {
+		t.Fatalf("GenerateSpeech(Strict) error = %v", err)
+	}
+
+	// The listing was fetched again once and stored.
+	narrators := 0
+	for _, call := range fake.Calls() {
+		if call[0] == "--list-narrator" {
+			narrators++
+		}
+	}
+	if narrators != 1 {
+		t.Fatalf("Calls() = %q, want the narrators listed again once", fake.Calls())
+	}
+}
+
+func TestCatalogFindsNewEmotion(t *testing.T) {
+	fake := vpeaktest.New(t)
+	client := fake.Client()
+	client.Catalog = vpeak.NewCatalog(filepath.Join(t.TempDir(), vpeak.CatalogName))
+	if _, err := client.ListEmotions(vpeaktest.DefaultNarrators[0]); err != nil {
+		t.Fatalf("ListEmotions() error = %v", err)
+	}
+
+	// Updating a narrator leaves the executable unchanged.
+	fake.Update(func(c *vpeaktest.Config) {
+		c.Emotions[vpeaktest.DefaultNarrators[0]] = append(c.Emotions[vpeaktest.DefaultNarrators[0]], "whisper")
+	})
+	fake.Reset()
+
+	output := filepath.Join(t.TempDir(), "whisper.wav")
+	opts := vpeak.Options{Narrator: vpeaktest.DefaultNarrators[0], Emotion: "whisper", Output: output, Silent: true, Strict: true}
+	if err := client.GenerateSpeech("こんにちは", opts); err != nil {
+		t.Fatalf("GenerateSpeech(Strict) error = %v", err)
+	}
+
+	// An emotion missing after the refresh is still rejected.
+	opts.Emotion = "shout"
+	if err := client.GenerateSpeech("こんにちは", opts); !errors.Is(err, vpeak.ErrInvalidEmotion) {
+		t.Fatalf("GenerateSpeech(Strict) error = %v, want ErrInvalidEmotion", err)
+	}
+}
+
+func TestCatalogUnwritable(t *testing.T) {
+	fake := vpeaktest.New(t)
+	client := fake.Client()
+	// A file in place of the catalog directory makes every write fail.
+	dir := filepath.Join(t.TempDir(), "cache")
+	if err := os.WriteFile(dir, nil, 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	client.Catalog = vpeak.NewCatalog(filepath.Join(dir, vpeak.CatalogName))
+
+	narrators, err := client.ListNarrators()
+	if err != nil {
+		t.Fatalf("ListNarrators() error = %v", err)
+	}
+	if !reflect.DeepEqual(narrators, vpeaktest.DefaultNarrators) {
+		t.Fatalf("ListNarrators() = %q, want %q", narrators, vpeaktest.DefaultNarrators)
+	}
+}
+
+func TestStrictProcessTextFilesValidatesOnce(t *testing.T) {
+	fake := vpeaktest.New(t)
+	client := fake.Client()
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("こんにちは"), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+	}
+
+	result, err := client.ProcessTextFiles(dir, vpeak.Options{Narrator: "f1", Emotion: "happy", Silent: true, Strict: true})
+	if err != nil {
+		t.Fatalf("ProcessTextFiles() error = %v", err)
+	}
+	if result.Succeeded() != 3 {
+		t.Fatalf("Succeeded() = %d, want 3", result.Succeeded())
+	}
+	// One narrator and one emotion listing, then a synthesis per file.
+	if calls := fake.Calls(); len(calls) != 5 {
+		t.Fatalf("Calls() = %q, want the listings run once", calls)
+	}
+}
+
+func TestGenerateSpeechStrict(t *testing.T) {
+	fake := vpeaktest.New(t)
+	fake.Update(func(c *vpeaktest.Config) {
+		c.Narrators = []string{"宮舞モカ", "夏色花梨"}
+		c.Emotions = map[string][]string{"宮舞モカ": {"amaama", "live"}, "夏色花梨": {"hightension"}}
+	})
+	client := fake.Client()
+	client.Catalog = vpeak.NewCatalog(filepath.Join(t.TempDir(), vpeak.CatalogName))
+	output := filepath.Join(t.TempDir(), "strict.wav")
+
+	tests := []struct {
+		name    string
+		opts    vpeak.Options
+		wantErr error
+		wantMsg string
+	}{
+		{name: "prefix", opts: vpeak.Options{Narrator: "宮舞", Emotion: "live=40"}},
+		{name: "unknown narrator", opts: vpeak.Options{Narrator: "宮舞モ力"}, wantErr: vpeak.ErrUnknownNarrator, wantMsg: `did you mean "宮舞モカ"`},
+		{name: "unknown emotion", opts: vpeak.Options{Narrator: "夏色花梨", Emotion: "happy"}, wantErr: vpeak.ErrInvalidEmotion, wantMsg: "available: hightension"},
+		{name: "bad weight", opts: vpeak.Options{Narrator: "夏色花梨", Emotion: "hightension=120"}, wantErr: vpeak.ErrInvalidEmotion},
+		{name: "bad syntax without narrator", opts: vpeak.Options{Emotion: "happy=,"}, wantErr: vpeak.ErrInvalidEmotion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.Reset()
+			opts := tt.opts
+			opts.Output = output
+			opts.Silent = true
+			opts.Strict = true
+
+			err := client.GenerateSpeech("こんにちは", opts)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("GenerateSpeech() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) || !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Fatalf("GenerateSpeech() error = %v, want %v containing %q", err, tt.wantErr, tt.wantMsg)
+			}
+			for _, call := range fake.Calls() {
+				if call[0] == "-o" {
+					t.Fatalf("GenerateSpeech() ran VOICEPEAK with %q after failing validation", call)
+				}
+			}
+		})
+	}
+
+	// The listings were fetched once and the prefix resolved.
+	fake.Reset()
+	if err := client.GenerateSpeech("こんにちは", vpeak.Options{Narrator: "宮舞", Output: output, Silent: true, Strict: true}); err != nil {
+		t.Fatalf("GenerateSpeech() error = %v", err)
+	}
+	calls := fake.Calls()
+	if len(calls) != 1 || !reflect.DeepEqual(calls[0][2:4], []string{"--narrator", "宮舞モカ"}) {
+		t.Fatalf("Calls() = %q, want one synthesis with the resolved narrator", calls)
+	}
+}
@@ -0,0 +1,112 @@
+package vpeak
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultMaxChunkLength is the number of characters VOICEPEAK reliably accepts
+// in a single call.
+const DefaultMaxChunkLength = 140
+
+// SplitText splits text into chunks of at most maxLen characters for separate
+// VOICEPEAK calls. Chunks break after sentence endings (。！？!?) and newlines;
+// sentences that are still too long are broken after 、 and, as a last resort,
+// at maxLen. Consecutive short sentences are packed into one chunk.
+func SplitText(text string, maxLen int) []string {
+	if maxLen <= 0 {
+		maxLen = DefaultMaxChunkLength
+	}
+
+	var pieces []string
+	for _, sentence := range splitSentences(text) {
+		if utf8.RuneCountInString(sentence) <= maxLen {
+			pieces = append(pieces, sentence)
+			continue
+		}
+		for _, clause := range splitAfter(sentence, "、，,") {
+			pieces = append(pieces, splitRunes(clause, maxLen)...)
+		}
+	}
+
+	var chunks []string
+	var current string
+	for _, piece := range pieces {
+		joined := joinChunk(current, piece)
+		if current != "" && utf8.RuneCountInString(joined) > maxLen {
+			chunks = append(chunks, current)
+			joined = piece
+		}
+		current = joined
+	}
+	if current != "" {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
+
+// splitSentences breaks text after sentence-ending punctuation and at
+// newlines. Closing brackets directly after the punctuation stay with the
+// sentence. Empty sentences are dropped.
+func splitSentences(text string) []string {
+	var sentences []string
+	for _, line := range strings.Split(text, "\n") {
+		sentences = append(sentences, splitAfter(line, "。！？!?")...)
+	}
+	return sentences
+}
+
+func splitAfter(s, delimiters string) []string {
+	var parts []string
+	runes := []rune(s)
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		if !strings.ContainsRune(delimiters, runes[i]) {
+			continue
+		}
+		for i+1 < len(runes) && (strings.ContainsRune(delimiters, runes[i+1]) || isClosingBracket(runes[i+1])) {
+			i++
+		}
+		parts = appendTrimmed(parts, string(runes[start:i+1]))
+		start = i + 1
+	}
+	return appendTrimmed(parts, string(runes[start:]))
+}
+
+func splitRunes(s string, maxLen int) []string {
+	var parts []string
+	runes := []rune(s)
+	for len(runes) > maxLen {
+		parts = appendTrimmed(parts, string(runes[:maxLen]))
+		runes = runes[maxLen:]
+	}
+	return appendTrimmed(parts, string(runes))
+}
+
+func appendTrimmed(parts []string, s string) []string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return parts
+	}
+	return append(parts, s)
+}
+
+func isClosingBracket(r rune) bool {
+	return strings.ContainsRune("」』）)】〉》\"'", r)
+}
+
+// joinChunk concatenates two pieces of text, inserting a space only between
+// words of space-separated scripts.
+func joinChunk(a, b string) string {
+	if a == "" {
+		return b
+	}
+	last, _ := utf8.DecodeLastRuneInString(a)
+	first, _ := utf8.DecodeRuneInString(b)
+	if last < utf8.RuneSelf && first < utf8.RuneSelf && !unicode.IsSpace(last) {
+		return a + " " + b
+	}
+	return a + b
+}
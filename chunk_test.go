@@ -0,0 +1,44 @@
+package vpeak
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		maxLen int
+		want   []string
+	}{
+		{"empty", "", 10, nil},
+		{"short text is one chunk", "こんにちは。", 10, []string{"こんにちは。"}},
+		{"sentences are packed", "あい。うえ。おか。", 6, []string{"あい。うえ。", "おか。"}},
+		{"newline breaks sentences", "あいう\nえお", 4, []string{"あいう", "えお"}},
+		{"closing bracket stays with sentence", "「はい！」と言った。", 6, []string{"「はい！」", "と言った。"}},
+		{"falls back to comma", "あいうえ、かきくけ。", 6, []string{"あいうえ、", "かきくけ。"}},
+		{"hard split", "あいうえおかき", 3, []string{"あいう", "えおか", "き"}},
+		{"ascii words keep a space", "Hello!\nWorld!", 20, []string{"Hello! World!"}},
+		{"blank lines dropped", "あ。\n\n  \nい。", 2, []string{"あ。", "い。"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitText(tt.text, tt.maxLen); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("SplitText(%q, %d) = %#v, want %#v", tt.text, tt.maxLen, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitTextDefaultMaxLength(t *testing.T) {
+	text := strings.Repeat("あいうえお。", 50)
+	for _, chunk := range SplitText(text, 0) {
+		if n := utf8.RuneCountInString(chunk); n > DefaultMaxChunkLength {
+			t.Fatalf("chunk length = %d, want <= %d", n, DefaultMaxChunkLength)
+		}
+	}
+}
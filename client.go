@@ -0,0 +1,492 @@
+package vpeak
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/shinshin86/vpeak/wav"
+)
+
+// Engine runs VOICEPEAK with the given command-line arguments and returns its
+// combined stdout and stderr output. Implementations should stop and return
+// ctx.Err() once ctx is done.
+type Engine interface {
+	Run(ctx context.Context, args []string) ([]byte, error)
+}
+
+// Client holds the configuration used to invoke VOICEPEAK. The zero value runs
+// the executable at VoicepeakPath.
+type Client struct {
+	// Path is the VOICEPEAK executable. If empty, VoicepeakPath is used.
+	Path string
+	// Env is the environment of the VOICEPEAK process. If nil, the current
+	// process environment is used.
+	Env []string
+	// Dir is the working directory of the VOICEPEAK process, against which
+	// relative output paths are resolved. If empty, the current directory is
+	// used.
+	Dir string
+	// Wine, if set, runs Path through Wine, translating output paths into
+	// Windows paths.
+	Wine *Wine
+	// Engine replaces the VOICEPEAK process, e.g. with a fake in tests. If nil,
+	// the executable at Path is run.
+	Engine Engine
+	// Player plays rendered audio. If nil, DefaultPlayer is used.
+	Player Player
+	// Cache stores rendered audio so that repeated calls with the same text
+	// and options skip VOICEPEAK. If nil, nothing is cached.
+	Cache *Cache
+	// Catalog stores the narrators and emotions VOICEPEAK lists, for
+	// ListNarrators, ListEmotions and Options.Strict. If nil, VOICEPEAK is
+	// asked every time.
+	Catalog *Catalog
+
+	// recorder, if set, collects the arguments of every synthesis call.
+	recorder *callRecorder
+}
+
+// DefaultClient is the client used by the package-level functions.
+var DefaultClient = &Client{}
+
+// NewClient returns a client that runs the VOICEPEAK executable at path.
+func NewClient(path string) *Client {
+	return &Client{Path: path}
+}
+
+// GenerateSpeech generates speech audio from the given text and options
+func (c *Client) GenerateSpeech(text string, opts Options) error {
+	return c.GenerateSpeechContext(context.Background(), text, opts)
+}
+
+// GenerateSpeechContext is like GenerateSpeech but stops VOICEPEAK and the
+// audio player when ctx is done.
+func (c *Client) GenerateSpeechContext(ctx context.Context, text string, opts Options) error {
+	opts, err := resolveProfile(opts)
+	if err != nil {
+		return err
+	}
+	if opts, err = c.validate(ctx, opts); err != nil {
+		return err
+	}
+	return c.generateSpeech(ctx, text, opts)
+}
+
+// generateSpeech renders and plays text with opts whose profile has been
+// resolved and validated.
+func (c *Client) generateSpeech(ctx context.Context, text string, opts Options) error {
+	switch opts.Format {
+	case "", FormatText:
+	case FormatMarkdown:
+		return c.generateMarkdownSpeech(ctx, text, opts)
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidFormat, opts.Format)
+	}
+
+	if err := c.synthesize(ctx, text, opts); err != nil {
+		return err
+	}
+
+	return c.playOutput(ctx, opts)
+}
+
+// SynthesizeTo renders text and writes the WAV bytes to w instead of a file.
+func (c *Client) SynthesizeTo(w io.Writer, text string, opts Options) error {
+	return c.SynthesizeToContext(context.Background(), w, text, opts)
+}
+
+// SynthesizeToContext is like SynthesizeTo but stops VOICEPEAK when ctx is
+// done. VOICEPEAK renders into a temporary file, which is removed after it
+// has been copied to w. opts.Output, opts.Silent, opts.Subtitles and
+// opts.SplitSections are ignored.
+func (c *Client) SynthesizeToContext(ctx context.Context, w io.Writer, text string, opts Options) error {
+	tempDir, err := os.MkdirTemp("", "vpeak-synthesize-")
+	if err != nil {
+		return fmt.Errorf("create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	opts.Output = filepath.Join(tempDir, WavName)
+	opts.Silent = true
+	opts.Subtitles = ""
+	opts.SplitSections = false
+	if err := c.GenerateSpeechContext(ctx, text, opts); err != nil {
+		return err
+	}
+
+	file, err := os.Open(opts.Output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(w, file); err != nil {
+		return fmt.Errorf("write audio: %w", err)
+	}
+	return nil
+}
+
+// PlayAudio plays the specified audio file with c.Player.
+func (c *Client) PlayAudio(wavName string) error {
+	return c.PlayAudioContext(context.Background(), wavName)
+}
+
+// PlayAudioContext is like PlayAudio but stops playback when ctx is done.
+func (c *Client) PlayAudioContext(ctx context.Context, wavName string) error {
+	player := c.Player
+	if player == nil {
+		var err error
+		if player, err = DefaultPlayer(); err != nil {
+			return err
+		}
+	}
+	return player.Play(ctx, wavName)
+}
+
+// playOutput plays the file rendered for opts unless opts.Silent is set.
+func (c *Client) playOutput(ctx context.Context, opts Options) error {
+	if opts.Silent {
+		return nil
+	}
+
+	output := c.outputPath(opts)
+	if err := c.PlayAudioContext(ctx, output); err != nil {
+		return err
+	}
+
+	// if the output is not specified, delete the generated wav file
+	if runtime.GOOS != "windows" && (opts.Output == "" || opts.Output == WavName) {
+		if err := os.Remove(output); err != nil {
+			return fmt.Errorf("failed to delete %s: %v", WavName, err)
+		}
+	}
+
+	return nil
+}
+
+// synthesize renders text into opts.Output, writing the subtitles requested
+// by opts.Subtitles next to it.
+func (c *Client) synthesize(ctx context.Context, text string, opts Options) error {
+	formats, err := parseSubtitleFormats(opts.Subtitles)
+	if err != nil {
+		return err
+	}
+
+	cues, err := c.render(ctx, text, opts, len(formats) > 0)
+	if err != nil {
+		return err
+	}
+
+	return writeSubtitles(c.outputPath(opts), formats, cues)
+}
+
+// render renders text into opts.Output. Text longer than opts.MaxChunkLength
+// is rendered piece by piece and the clips are joined. With timed set every
+// sentence is rendered separately and the returned cues give its position.
+func (c *Client) render(ctx context.Context, text string, opts Options, timed bool) ([]Cue, error) {
+	var chunks []string
+	if timed {
+		chunks = splitSubtitleText(text, opts.MaxChunkLength)
+	} else {
+		chunks = SplitText(text, opts.MaxChunkLength)
+	}
+	if len(chunks) == 0 || (len(chunks) == 1 && !timed) {
+		return nil, c.synthesizeChunk(ctx, text, opts)
+	}
+
+	tempDir, err := os.MkdirTemp("", "vpeak-chunks-")
+	if err != nil {
+		return nil, fmt.Errorf("create chunk directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	clips := make([]*wav.Audio, 0, len(chunks))
+	cues := make([]Cue, 0, len(chunks))
+	var offset time.Duration
+	for i, chunk := range chunks {
+		chunkOpts := opts
+		chunkOpts.Output = filepath.Join(tempDir, fmt.Sprintf("chunk-%03d.wav", i))
+		if err := c.synthesizeChunk(ctx, chunk, chunkOpts); err != nil {
+			return nil, err
+		}
+
+		clip, err := wav.ReadFile(chunkOpts.Output)
+		if err != nil {
+			return nil, fmt.Errorf("read chunk: %w", err)
+		}
+		clips = append(clips, clip)
+
+		if i > 0 {
+			offset += wav.Silence(clip.Format, opts.ChunkPause).Duration()
+		}
+		cues = append(cues, Cue{Start: offset, End: offset + clip.Duration(), Text: chunk})
+		offset += clip.Duration()
+	}
+
+	joined, err := wav.Concat(clips, opts.ChunkPause)
+	if err != nil {
+		return nil, fmt.Errorf("join chunks: %w", err)
+	}
+
+	return cues, joined.WriteFile(c.outputPath(opts))
+}
+
+func (c *Client) synthesizeChunk(ctx context.Context, text string, opts Options) error {
+	options, err := buildOptions(text, opts)
+	if err != nil {
+		return err
+	}
+
+	c.recorder.record(options)
+
+	var cacheKey string
+	if c.Cache != nil {
+		cacheKey = c.Cache.Key(c.executable(), options)
+		// The cache is best-effort: an unreadable entry is rendered again.
+		if hit, err := c.Cache.Get(cacheKey, c.outputPath(opts)); err == nil && hit {
+			return nil
+		}
+	}
+
+	if _, err := c.run(ctx, options); err != nil {
+		if ctx.Err() != nil || errors.Is(err, ErrVoicepeakNotFound) || errors.Is(err, ErrUnsupportedPlatform) {
+			return err
+		}
+		if opts.Strict && opts.Narrator != "" {
+			// The names were checked by validate.
+			return fmt.Errorf("voicepeak command failed: %w", err)
+		}
+		return fmt.Errorf("voicepeak command failed: %w "+
+			"(check that the specified narrator and emotion names are supported by VOICEPEAK)", err)
+	}
+
+	if c.Cache != nil {
+		// Failing to store the entry does not fail the rendered audio.
+		_ = c.Cache.Put(cacheKey, c.outputPath(opts))
+	}
+	return nil
+}
+
+// validate checks opts, applies the Strict checks and replaces its narrator
+// with the installed name. Entry points call it once before rendering. The
+// emotion names can only be checked with a narrator.
+func (c *Client) validate(ctx context.Context, opts Options) (Options, error) {
+	if opts.ChunkPause < 0 {
+		return Options{}, fmt.Errorf("chunk pause must not be negative: %s", opts.ChunkPause)
+	}
+	if opts.HeadingPause < 0 {
+		return Options{}, fmt.Errorf("heading pause must not be negative: %s", opts.HeadingPause)
+	}
+	if !opts.Strict {
+		return opts, nil
+	}
+
+	if opts.Emotion != "" {
+		if _, err := normalizeEmotionExpression(opts.Emotion); err != nil {
+			return Options{}, fmt.Errorf("%w %q: %v", ErrInvalidEmotion, opts.Emotion, err)
+		}
+	}
+	if opts.Narrator == "" {
+		return opts, nil
+	}
+
+	narrator, err := c.ResolveNarrator(ctx, opts.Narrator)
+	if err != nil {
+		return Options{}, err
+	}
+	opts.Narrator = narrator
+	if opts.Emotion == "" {
+		return opts, nil
+	}
+
+	emotions, err := c.ListEmotionsContext(ctx, narrator)
+	if err != nil {
+		return Options{}, err
+	}
+	_, err = ValidateEmotionExpression(opts.Emotion, emotions)
+	if err != nil && c.usesCatalog() {
+		// Updating a narrator can add emotions without changing the
+		// executable, so a cached listing is fetched again.
+		if fresh, listErr := c.list(ctx, true, "--list-emotion", narrator); listErr == nil {
+			emotions = fresh
+			_, err = ValidateEmotionExpression(opts.Emotion, emotions)
+		}
+	}
+	if err != nil {
+		return Options{}, fmt.Errorf("%w for narrator %q: %v (available: %s)", ErrInvalidEmotion, narrator, err, strings.Join(emotions, ", "))
+	}
+	return opts, nil
+}
+
+// executable returns the path of the VOICEPEAK executable.
+func (c *Client) executable() string {
+	if c.Path != "" {
+		return c.Path
+	}
+	return VoicepeakPath
+}
+
+// outputPath returns where VOICEPEAK writes the audio rendered for opts.
+func (c *Client) outputPath(opts Options) string {
+	output := opts.Output
+	if output == "" {
+		output = WavName
+	}
+	if filepath.IsAbs(output) {
+		return output
+	}
+	// VOICEPEAK resolves relative paths against its working directory.
+	return filepath.Join(c.Dir, output)
+}
+
+// ListNarrators returns narrator names installed in VOICEPEAK.
+func (c *Client) ListNarrators() ([]string, error) {
+	return c.ListNarratorsContext(context.Background())
+}
+
+// ListNarratorsContext is like ListNarrators but stops VOICEPEAK when ctx is done.
+func (c *Client) ListNarratorsContext(ctx context.Context) ([]string, error) {
+	return c.list(ctx, false, "--list-narrator")
+}
+
+// ListEmotions returns emotion names available for the given narrator.
+func (c *Client) ListEmotions(narrator string) ([]string, error) {
+	return c.ListEmotionsContext(context.Background(), narrator)
+}
+
+// ListEmotionsContext is like ListEmotions but stops VOICEPEAK when ctx is done.
+func (c *Client) ListEmotionsContext(ctx context.Context, narrator string) ([]string, error) {
+	narrator = resolveNarratorName(narrator)
+	if narrator == "" {
+		return nil, fmt.Errorf("narrator is required")
+	}
+
+	return c.list(ctx, false, "--list-emotion", narrator)
+}
+
+// Voice is an installed narrator and the emotions it supports.
+type Voice struct {
+	Narrator string   `json:"narrator"`
+	Emotions []string `json:"emotions"`
+}
+
+// ListVoices returns every installed narrator with its emotions.
+func (c *Client) ListVoices() ([]Voice, error) {
+	return c.ListVoicesContext(context.Background())
+}
+
+// ListVoicesContext is like ListVoices but stops VOICEPEAK when ctx is done.
+// VOICEPEAK is run once for the narrators and once for each narrator.
+func (c *Client) ListVoicesContext(ctx context.Context) ([]Voice, error) {
+	narrators, err := c.ListNarratorsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	voices := make([]Voice, 0, len(narrators))
+	for _, narrator := range narrators {
+		emotions, err := c.ListEmotionsContext(ctx, narrator)
+		if err != nil {
+			return nil, fmt.Errorf("narrator %q: %w", narrator, err)
+		}
+		voices = append(voices, Voice{Narrator: narrator, Emotions: emotions})
+	}
+	return voices, nil
+}
+
+// list runs VOICEPEAK with args and returns the names it prints, from the
+// catalog unless refresh is set.
+func (c *Client) list(ctx context.Context, refresh bool, args ...string) ([]string, error) {
+	if c.usesCatalog() {
+		return c.Catalog.list(c.executable(), args, refresh, func() ([]string, error) {
+			return c.runList(ctx, args)
+		})
+	}
+	return c.runList(ctx, args)
+}
+
+// usesCatalog reports whether listings go through c.Catalog. They are stored
+// per executable, which a custom Engine replaces.
+func (c *Client) usesCatalog() bool {
+	return c.Catalog != nil && c.Engine == nil
+}
+
+func (c *Client) runList(ctx context.Context, args []string) ([]string, error) {
+	output, err := c.run(ctx, args)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("voicepeak command failed: %w", err)
+	}
+
+	return parseVoicepeakListOutput(string(output)), nil
+}
+
+func (c *Client) run(ctx context.Context, args []string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if c.Engine != nil {
+		return c.Engine.Run(ctx, args)
+	}
+
+	cmd, err := c.command(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return output, ctxErr
+		}
+		return output, commandError(err, output)
+	}
+	return output, nil
+}
+
+func (c *Client) command(ctx context.Context, args []string) (*exec.Cmd, error) {
+	if c.Wine != nil {
+		return c.wineCommand(ctx, args)
+	}
+
+	cmd, err := vpCmd(ctx, c.executable(), args)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.Env = c.Env
+	cmd.Dir = c.Dir
+	return cmd, nil
+}
+
+func (c *Client) wineCommand(ctx context.Context, args []string) (*exec.Cmd, error) {
+	executable := c.executable()
+	if _, err := os.Stat(executable); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVoicepeakNotFound, err)
+	}
+
+	args, err := c.Wine.translateArgs(c.Dir, args)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd, err := vpCmd(ctx, c.Wine.command(), append([]string{executable}, args...))
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.Env = c.Wine.environ(c.Env)
+	cmd.Dir = c.Dir
+	return cmd, nil
+}
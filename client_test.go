@@ -0,0 +1,289 @@
+package vpeak
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shinshin86/vpeak/wav"
+)
+
+type fakeEngine struct {
+	mu     sync.Mutex
+	calls  [][]string
+	output string
+	err    error
+	hang   bool
+	// audio is written to the path following -o, if set.
+	audio []byte
+}
+
+func (e *fakeEngine) Run(ctx context.Context, args []string) ([]byte, error) {
+	e.mu.Lock()
+	e.calls = append(e.calls, append([]string(nil), args...))
+	e.mu.Unlock()
+	if e.hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if e.audio != nil {
+		for i, arg := range args {
+			if arg == "-o" && i+1 < len(args) {
+				if err := os.WriteFile(args[i+1], e.audio, 0o644); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return []byte(e.output), e.err
+}
+
+// testWAV returns a 16-bit mono 8 kHz WAV file with the given number of frames.
+func testWAV(frames int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+frames*2))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, []uint32{16})
+	binary.Write(&buf, binary.LittleEndian, []uint16{1, 1})
+	binary.Write(&buf, binary.LittleEndian, []uint32{8000, 16000})
+	binary.Write(&buf, binary.LittleEndian, []uint16{2, 16})
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(frames*2))
+	buf.Write(bytes.Repeat([]byte{1, 0}, frames))
+	return buf.Bytes()
+}
+
+func TestClientGenerateSpeechUsesEngine(t *testing.T) {
+	engine := &fakeEngine{}
+	client := &Client{Engine: engine}
+
+	err := client.GenerateSpeech("hi", Options{Narrator: "f1", Output: "hi.wav", Silent: true})
+	if err != nil {
+		t.Fatalf("GenerateSpeech() error = %v", err)
+	}
+
+	want := [][]string{{"-o", "hi.wav", "--narrator", "Japanese Female 1", "-s", "hi"}}
+	if !reflect.DeepEqual(engine.calls, want) {
+		t.Fatalf("engine calls = %#v, want %#v", engine.calls, want)
+	}
+}
+
+func TestClientGenerateSpeechEngineError(t *testing.T) {
+	errBoom := errors.New("boom")
+	client := &Client{Engine: &fakeEngine{err: errBoom}}
+
+	err := client.GenerateSpeech("hi", Options{Silent: true})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("GenerateSpeech() error = %v, want %v", err, errBoom)
+	}
+}
+
+func TestClientListEmotions(t *testing.T) {
+	engine := &fakeEngine{output: "[debug] noise\nhappy\nsad\n"}
+	client := &Client{Engine: engine}
+
+	got, err := client.ListEmotions("f2")
+	if err != nil {
+		t.Fatalf("ListEmotions() error = %v", err)
+	}
+	if want := []string{"happy", "sad"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ListEmotions() = %#v, want %#v", got, want)
+	}
+
+	wantCalls := [][]string{{"--list-emotion", "Japanese Female 2"}}
+	if !reflect.DeepEqual(engine.calls, wantCalls) {
+		t.Fatalf("engine calls = %#v, want %#v", engine.calls, wantCalls)
+	}
+}
+
+func TestClientListEmotionsRequiresNarrator(t *testing.T) {
+	engine := &fakeEngine{}
+	client := &Client{Engine: engine}
+
+	if _, err := client.ListEmotions(""); err == nil {
+		t.Fatal("ListEmotions(\"\") error = nil, want error")
+	}
+	if len(engine.calls) != 0 {
+		t.Fatalf("engine calls = %#v, want none", engine.calls)
+	}
+}
+
+func TestClientGenerateSpeechInvalidEmotion(t *testing.T) {
+	engine := &fakeEngine{}
+	client := &Client{Engine: engine}
+
+	err := client.GenerateSpeech("hi", Options{Emotion: "happy=,", Silent: true})
+	if !errors.Is(err, ErrInvalidEmotion) {
+		t.Fatalf("GenerateSpeech() error = %v, want ErrInvalidEmotion", err)
+	}
+	if len(engine.calls) != 0 {
+		t.Fatalf("engine calls = %#v, want none", engine.calls)
+	}
+}
+
+func TestClientGenerateSpeechNegativeChunkPause(t *testing.T) {
+	engine := &fakeEngine{}
+	client := &Client{Engine: engine}
+
+	err := client.GenerateSpeech("hello world", Options{MaxChunkLength: 5, ChunkPause: -300 * time.Millisecond, Silent: true})
+	if err == nil {
+		t.Fatal("GenerateSpeech() error = nil, want error")
+	}
+	if len(engine.calls) != 0 {
+		t.Fatalf("engine calls = %#v, want none", engine.calls)
+	}
+}
+
+func TestClientVoicepeakNotFound(t *testing.T) {
+	client := NewClient("/nonexistent/voicepeak")
+
+	_, err := client.ListNarrators()
+	if !errors.Is(err, ErrVoicepeakNotFound) {
+		t.Fatalf("ListNarrators() error = %v, want ErrVoicepeakNotFound", err)
+	}
+}
+
+func TestClientGenerateSpeechContextDeadline(t *testing.T) {
+	client := &Client{Engine: &fakeEngine{hang: true}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := client.GenerateSpeechContext(ctx, "hi", Options{Silent: true})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GenerateSpeechContext() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestClientProcessTextFilesContextCanceled(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("hi"), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+	}
+
+	engine := &fakeEngine{}
+	client := &Client{Engine: engine}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := client.ProcessTextFilesContext(ctx, dir, Options{Silent: true})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ProcessTextFilesContext() error = %v, want context.Canceled", err)
+	}
+	if failed := result.Failed(); len(failed) != 2 {
+		t.Fatalf("Failed() = %#v, want both files", failed)
+	}
+	if len(engine.calls) != 0 {
+		t.Fatalf("engine calls = %#v, want none", engine.calls)
+	}
+}
+
+func TestClientKillsHungVoicepeak(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	path := filepath.Join(t.TempDir(), "voicepeak")
+	script := "#!/bin/sh\nsleep 30 &\nwait\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := NewClient(path).ListNarratorsContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ListNarratorsContext() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("ListNarratorsContext() returned after %v, want prompt return", elapsed)
+	}
+}
+
+func TestClientGenerateSpeechSplitsLongText(t *testing.T) {
+	engine := &fakeEngine{audio: testWAV(100)}
+	client := &Client{Engine: engine}
+	output := filepath.Join(t.TempDir(), "out.wav")
+
+	err := client.GenerateSpeech("あいう。えお。", Options{
+		Output:         output,
+		Silent:         true,
+		MaxChunkLength: 4,
+		ChunkPause:     10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("GenerateSpeech() error = %v", err)
+	}
+
+	if len(engine.calls) != 2 {
+		t.Fatalf("engine calls = %d, want 2", len(engine.calls))
+	}
+	for i, want := range []string{"あいう。", "えお。"} {
+		if got := engine.calls[i][len(engine.calls[i])-1]; got != want {
+			t.Fatalf("chunk %d text = %q, want %q", i, got, want)
+		}
+	}
+
+	clip, err := wav.ReadFile(output)
+	if err != nil {
+		t.Fatalf("wav.ReadFile() error = %v", err)
+	}
+	// two clips of 100 frames plus 10ms (80 frames) of silence, 2 bytes each
+	if want := (100 + 80 + 100) * 2; len(clip.Data) != want {
+		t.Fatalf("data length = %d, want %d", len(clip.Data), want)
+	}
+}
+
+func TestClientSynthesizeTo(t *testing.T) {
+	audio := testWAV(10)
+	engine := &fakeEngine{audio: audio}
+	client := &Client{Engine: engine}
+
+	var buf bytes.Buffer
+	err := client.SynthesizeTo(&buf, "hi", Options{Output: "ignored.wav", Subtitles: SubtitleSRT})
+	if err != nil {
+		t.Fatalf("SynthesizeTo() error = %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), audio) {
+		t.Fatalf("SynthesizeTo() wrote %d bytes, want the rendered WAV", buf.Len())
+	}
+
+	output := engine.calls[0][1]
+	if filepath.Base(output) != WavName || output == WavName {
+		t.Fatalf("VOICEPEAK output = %q, want a temporary file", output)
+	}
+	if _, err := os.Stat(filepath.Dir(output)); !os.IsNotExist(err) {
+		t.Fatalf("temporary directory %s not removed", filepath.Dir(output))
+	}
+	if _, err := os.Stat("ignored.wav"); !os.IsNotExist(err) {
+		t.Fatal("ignored.wav written, want only w")
+	}
+}
+
+func TestClientCatalogSkippedWithEngine(t *testing.T) {
+	engine := &fakeEngine{output: "Japanese Female 1\n"}
+	client := &Client{Engine: engine, Catalog: NewCatalog(filepath.Join(t.TempDir(), CatalogName))}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListNarrators(); err != nil {
+			t.Fatalf("ListNarrators() error = %v", err)
+		}
+	}
+	if len(engine.calls) != 2 {
+		t.Fatalf("engine calls = %d, want every listing run", len(engine.calls))
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shinshin86/vpeak"
+)
+
+// stdinIsPiped reports whether standard input is a pipe or a file rather than
+// a terminal.
+func stdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice == 0
+}
+
+// selectInput decides where the text comes from when neither -f nor -d is
+// given: "-" for standard input, or "" for the text argument. Standard input
+// is read if it is requested with "-", piped, or streamed with -lines, even
+// from a terminal. It reports false if there is no text to speak.
+func selectInput(args []string, lines, piped bool) (string, bool) {
+	switch {
+	case len(args) > 0 && args[0] == "-":
+		return "-", true
+	case len(args) == 0 && (piped || lines):
+		return "-", true
+	case len(args) == 0 || lines:
+		return "", false
+	}
+	return "", true
+}
+
+// openInput opens the file at path, or standard input if path is "-".
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// readInput reads the whole text at path, or standard input if path is "-".
+func readInput(path string) (string, error) {
+	r, err := openInput(path)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// speakLines speaks each non-blank line of r as soon as it has been read. If
+// opts.Output is set, line n is written to the output name with a -000n
+// suffix. A line that fails is reported and skipped; a missing VOICEPEAK
+// stops the stream, and so does ctx being done.
+func speakLines(ctx context.Context, r io.Reader, opts vpeak.Options, timeout time.Duration) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	n := 0
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		n++
+
+		lineOpts := opts
+		if opts.Output != "" {
+			ext := filepath.Ext(opts.Output)
+			lineOpts.Output = fmt.Sprintf("%s-%04d%s", strings.TrimSuffix(opts.Output, ext), n, ext)
+		}
+
+		if err := speakLine(ctx, line, lineOpts, timeout); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, vpeak.ErrVoicepeakNotFound) || errors.Is(err, vpeak.ErrUnsupportedPlatform) {
+				return err
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				err = fmt.Errorf("timed out after %s", timeout)
+			}
+			log.Printf("Error speaking line %d: %v", n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// speakLine speaks line, giving up after timeout if it is positive.
+func speakLine(ctx context.Context, line string, opts vpeak.Options, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return vpeak.GenerateSpeechContext(ctx, line, opts)
+}
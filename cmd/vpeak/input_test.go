@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/shinshin86/vpeak"
+)
+
+// lineEngine records the text and output of each synthesis. Text "fail"
+// fails, and text "missing" reports that VOICEPEAK is not installed.
+type lineEngine struct {
+	mu    sync.Mutex
+	texts []string
+	paths []string
+}
+
+func (e *lineEngine) Run(ctx context.Context, args []string) ([]byte, error) {
+	var text, output string
+	for i := 0; i+1 < len(args); i++ {
+		switch args[i] {
+		case "-s":
+			text = args[i+1]
+		case "-o":
+			output = args[i+1]
+		}
+	}
+
+	e.mu.Lock()
+	e.texts = append(e.texts, text)
+	e.paths = append(e.paths, output)
+	e.mu.Unlock()
+
+	switch text {
+	case "fail":
+		return nil, errors.New("exit status 1")
+	case "missing":
+		return nil, fmt.Errorf("%w: voicepeak", vpeak.ErrVoicepeakNotFound)
+	}
+	return nil, nil
+}
+
+// useEngine makes the package-level functions run engine for the test.
+func useEngine(t *testing.T, engine vpeak.Engine) {
+	t.Helper()
+	previous := vpeak.DefaultClient
+	vpeak.DefaultClient = &vpeak.Client{Engine: engine}
+	t.Cleanup(func() { vpeak.DefaultClient = previous })
+}
+
+func TestSpeakLines(t *testing.T) {
+	engine := &lineEngine{}
+	useEngine(t, engine)
+
+	input := "one\n\n   \ntwo\nfail\nthree\n"
+	if err := speakLines(context.Background(), strings.NewReader(input), vpeak.Options{Output: "log.wav", Silent: true}, 0); err != nil {
+		t.Fatalf("speakLines() error = %v", err)
+	}
+
+	if want := []string{"one", "two", "fail", "three"}; !reflect.DeepEqual(engine.texts, want) {
+		t.Fatalf("texts = %q, want %q", engine.texts, want)
+	}
+	want := []string{"log-0001.wav", "log-0002.wav", "log-0003.wav", "log-0004.wav"}
+	if !reflect.DeepEqual(engine.paths, want) {
+		t.Fatalf("outputs = %q, want %q", engine.paths, want)
+	}
+}
+
+func TestSpeakLinesStopsWithoutVoicepeak(t *testing.T) {
+	engine := &lineEngine{}
+	useEngine(t, engine)
+
+	err := speakLines(context.Background(), strings.NewReader("one\nmissing\nthree\n"), vpeak.Options{Silent: true}, 0)
+	if !errors.Is(err, vpeak.ErrVoicepeakNotFound) {
+		t.Fatalf("speakLines() error = %v, want ErrVoicepeakNotFound", err)
+	}
+	if want := []string{"one", "missing"}; !reflect.DeepEqual(engine.texts, want) {
+		t.Fatalf("texts = %q, want %q", engine.texts, want)
+	}
+}
+
+func TestSpeakLinesStopsWhenCanceled(t *testing.T) {
+	engine := &lineEngine{}
+	useEngine(t, engine)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := speakLines(ctx, strings.NewReader("one\ntwo\n"), vpeak.Options{Silent: true}, 0)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("speakLines() error = %v, want context.Canceled", err)
+	}
+	if len(engine.texts) != 0 {
+		t.Fatalf("texts = %q, want none", engine.texts)
+	}
+}
+
+func TestReadInput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("こんにちは\n"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	text, err := readInput(path)
+	if err != nil {
+		t.Fatalf("readInput() error = %v", err)
+	}
+	if text != "こんにちは\n" {
+		t.Fatalf("readInput() = %q, want the file contents", text)
+	}
+
+	if _, err := readInput(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Fatal("readInput(missing) error = nil, want an error")
+	}
+}
+
+func TestSelectInput(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		lines     bool
+		piped     bool
+		wantInput string
+		wantOK    bool
+	}{
+		{name: "text", args: []string{"hi"}, wantOK: true},
+		{name: "dash", args: []string{"-"}, wantInput: "-", wantOK: true},
+		{name: "piped", piped: true, wantInput: "-", wantOK: true},
+		{name: "text wins over pipe", args: []string{"hi"}, piped: true, wantOK: true},
+		{name: "lines from terminal", lines: true, wantInput: "-", wantOK: true},
+		{name: "lines with text", args: []string{"hi"}, lines: true},
+		{name: "nothing", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, ok := selectInput(tt.args, tt.lines, tt.piped)
+			if input != tt.wantInput || ok != tt.wantOK {
+				t.Fatalf("selectInput() = %q, %v, want %q, %v", input, ok, tt.wantInput, tt.wantOK)
+			}
+		})
+	}
+}
@@ -1,23 +1,57 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/shinshin86/vpeak"
+	"github.com/shinshin86/vpeak/server"
 )
 
 var version = "dev"
 
+// voicepeakPathUsage documents the -voicepeak-path flag shared by the commands.
+const voicepeakPathUsage = "VOICEPEAK executable (default $VPEAK_VOICEPEAK_PATH, the config file, PATH, then the default install location; .exe runs through Wine)"
+
 func main() {
-	if len(os.Args) > 1 && os.Args[1] == "dict" {
-		runDictCommand(os.Args[2:])
-		return
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "dict":
+			runDictCommand(os.Args[2:])
+			return
+		case "script":
+			runScriptCommand(os.Args[2:])
+			return
+		case "serve":
+			runServeCommand(os.Args[2:])
+			return
+		case "openai-serve":
+			runOpenAIServeCommand(os.Args[2:])
+			return
+		case "cache":
+			runCacheCommand(os.Args[2:])
+			return
+		case "narrators":
+			runNarratorsCommand(os.Args[2:])
+			return
+		case "emotions":
+			runEmotionsCommand(os.Args[2:])
+			return
+		case "voices":
+			runVoicesCommand(os.Args[2:])
+			return
+		}
 	}
 
 	runSpeakCommand(os.Args[1:])
@@ -28,38 +62,71 @@ func runSpeakCommand(args []string) {
 
 	var (
 		dirOpt      = flagSet.String("d", "", "Directory to read files from")
-		outputOpt   = flagSet.String("o", "", "Output file path (Specify the name of the output directory if reading by directory (-d option))")
+		fileOpt     = flagSet.String("f", "", "File to read the text from (- for standard input)")
+		linesOpt    = flagSet.Bool("lines", false, "Speak each line of standard input (or -f) as it arrives; with -o, line n is written to name-000n.wav")
+		outputOpt   = flagSet.String("o", "", "Output file path, or - to write the WAV to standard output (Specify the name of the output directory if reading by directory (-d option))")
 		narratorOpt = flagSet.String("n", "", "Specify the narrator. See below for options.")
 		emotionOpt  = flagSet.String("e", "", "Specify the emotion. See below for options.")
 		speedOpt    = flagSet.String("speed", "", "Specify the speech speed (50-200)")
 		pitchOpt    = flagSet.String("pitch", "", "Specify the pitch adjustment (-300 - 300)")
+		profileOpt  = flagSet.String("profile", "", "Use a named profile of the config file; explicit flags override its values")
+		strictOpt   = flagSet.Bool("strict", false, "Check the narrator and emotion against those VOICEPEAK lists before rendering")
 		silentOpt   = flagSet.Bool("silent", false, "Silent mode (no sound)")
+		vpPathOpt   = flagSet.String("voicepeak-path", "", voicepeakPathUsage)
+		playerOpt   = flagSet.String("player", "", "Audio player: aplay, paplay, pw-play, ffplay, mpv or a command line (default $VPEAK_PLAYER or auto-detect)")
+		maxChunkOpt = flagSet.Int("max-chunk", vpeak.DefaultMaxChunkLength, "Maximum characters per VOICEPEAK call; longer text is split and joined")
+		pauseOpt    = flagSet.Duration("chunk-pause", 0, "Silence inserted between split chunks (e.g. 300ms)")
+		subtitleOpt = flagSet.String("subtitles", "", "Write subtitles next to the output (srt, vtt or srt,vtt)")
+		timeoutOpt  = flagSet.Duration("timeout", 0, "Abort if VOICEPEAK and playback do not finish within this duration (e.g. 30s, 0 disables)")
+		noCacheOpt  = flagSet.Bool("no-cache", false, "Always run VOICEPEAK instead of reusing cached audio")
+		workersOpt  = flagSet.Int("workers", 1, "Number of files rendered at the same time with -d")
+		resumeOpt   = flagSet.Bool("resume", false, "With -d, skip files already rendered from the same text and options, as recorded in .vpeak-manifest.json in the output directory (the input directory without -o)")
+		forceOpt    = flagSet.Bool("force", false, "With -d, render every file even if -resume is set")
+		recurseOpt  = flagSet.Bool("r", false, "With -d, also process subdirectories, mirroring them under -o")
+		extOpt      = flagSet.String("ext", "", "With -d, comma-separated extensions of the files to read (default txt, or md,markdown with -format md)")
+		formatOpt   = flagSet.String("format", vpeak.FormatText, "Input format: text or md (Markdown prose; code blocks and images are skipped)")
+		headingOpt  = flagSet.Duration("heading-pause", vpeak.DefaultHeadingPause, "With -format md, silence inserted before each heading")
+		sectionsOpt = flagSet.Bool("split-sections", false, "With -format md, write one WAV per heading section (name-01.wav, ...)")
+		includeOpt  listFlag
+		excludeOpt  listFlag
 		versionOpt  = flagSet.Bool("version", false, "Show version")
 		helpOpt     = flagSet.Bool("help", false, "Show help")
 	)
 
+	flagSet.Var(&includeOpt, "include", "With -d, only read files matching this glob (repeatable, e.g. 'ch*.txt' or 'part1/*')")
+	flagSet.Var(&excludeOpt, "exclude", "With -d, skip files and directories matching this glob (repeatable)")
+
 	flagSet.Usage = func() {
 		fmt.Printf("Usage: %s [OPTIONS] <text>\n", os.Args[0])
+		fmt.Printf("       %s [OPTIONS] -f <file>\n", os.Args[0])
+		fmt.Printf("       <command> | %s [OPTIONS] [-]\n", os.Args[0])
 		fmt.Println("Options:")
 		flagSet.PrintDefaults()
 		fmt.Println("\nNarrator options:")
-		fmt.Println("  f1: Japanese Female 1")
-		fmt.Println("  f2: Japanese Female 2")
-		fmt.Println("  f3: Japanese Female 3")
-		fmt.Println("  m1: Japanese Male 1")
-		fmt.Println("  m2: Japanese Male 2")
-		fmt.Println("  m3: Japanese Male 3")
-		fmt.Println("  c:  Japanese Female Child")
-		fmt.Println("  Other installed VOICEPEAK narrator names are also accepted.")
-		fmt.Println("  Run VOICEPEAK with --list-narrator to inspect installed narrators.")
+		printNarratorAliases()
+		fmt.Println("  Other installed VOICEPEAK narrator names, or a unique prefix, are also accepted.")
+		fmt.Println("  Add aliases with \"narrator_aliases\" in the config file.")
+		fmt.Printf("  Run %s narrators to list installed narrators.\n", os.Args[0])
 		fmt.Println("\nEmotion options (values 0-100, comma-separate multiple):")
 		fmt.Println("  Emotion names depend on the selected narrator.")
-		fmt.Println("  Run VOICEPEAK with --list-emotion <Narrator> to inspect available emotions.")
+		fmt.Printf("  Run %s emotions <narrator> to list its emotions.\n", os.Args[0])
 		fmt.Println("\nEmotion examples:")
 		fmt.Println("  happy              (equivalent to happy=100)")
 		fmt.Println("  happy=50")
 		fmt.Println("  happy=40,fun=60")
 		fmt.Println("  amaama=40,live=60")
+		fmt.Println("\nProfiles:")
+		fmt.Println("  Defaults and named profiles are read from the config file")
+		fmt.Println("  ($VPEAK_CONFIG or ~/.config/vpeak/config.json) and ./.vpeak.json.")
+		fmt.Println("\nVoices:")
+		fmt.Printf("  %s narrators|emotions|voices -h\n", os.Args[0])
+		fmt.Println("\nDialogue scripts:")
+		fmt.Printf("  %s script -h\n", os.Args[0])
+		fmt.Println("\nSynthesis cache:")
+		fmt.Printf("  %s cache stats|clear\n", os.Args[0])
+		fmt.Println("\nHTTP server:")
+		fmt.Printf("  %s serve -h\n", os.Args[0])
+		fmt.Printf("  %s openai-serve -h\n", os.Args[0])
 		fmt.Println("\nDictionary commands:")
 		fmt.Printf("  %s dict -h\n", os.Args[0])
 	}
@@ -78,8 +145,14 @@ func runSpeakCommand(args []string) {
 		os.Exit(0)
 	}
 
-	if len(flagSet.Args()) == 0 && *dirOpt == "" {
-		log.Fatalf("Usage: %s [-n] <text>", os.Args[0])
+	config := useConfig()
+
+	input := *fileOpt
+	if input == "" && *dirOpt == "" {
+		var ok bool
+		if input, ok = selectInput(flagSet.Args(), *linesOpt, stdinIsPiped()); !ok {
+			log.Fatalf("Usage: %s [-n] <text>", os.Args[0])
+		}
 	}
 
 	opts := vpeak.Options{
@@ -87,6 +160,21 @@ func runSpeakCommand(args []string) {
 		Emotion:  *emotionOpt,
 		Output:   *outputOpt,
 		Silent:   *silentOpt,
+		Strict:   *strictOpt,
+
+		MaxChunkLength: *maxChunkOpt,
+		ChunkPause:     *pauseOpt,
+		Subtitles:      *subtitleOpt,
+		Format:         *formatOpt,
+		HeadingPause:   *headingOpt,
+		SplitSections:  *sectionsOpt,
+		Workers:        *workersOpt,
+		Resume:         *resumeOpt,
+		Force:          *forceOpt,
+		Recursive:      *recurseOpt,
+		Extensions:     splitList(*extOpt),
+		Include:        includeOpt,
+		Exclude:        excludeOpt,
 	}
 
 	if *speedOpt != "" {
@@ -113,20 +201,453 @@ func runSpeakCommand(args []string) {
 		opts.Pitch = &pitch
 	}
 
-	if *dirOpt == "" {
-		text := flagSet.Args()[0]
-		if err := vpeak.GenerateSpeech(text, opts); err != nil {
+	applyProfile(flagSet, &opts, config, *profileOpt)
+
+	if opts.ChunkPause < 0 {
+		log.Fatalf("Chunk pause must not be negative")
+	}
+	if opts.HeadingPause < 0 {
+		log.Fatalf("Heading pause must not be negative")
+	}
+
+	toStdout := *outputOpt == "-"
+	if toStdout && (*dirOpt != "" || *linesOpt) {
+		log.Fatalf("Error: -o - cannot be combined with -d or -lines")
+	}
+
+	useVoicepeak(*vpPathOpt, true)
+	useCache(*noCacheOpt)
+	useCatalog()
+	usePlayer(*playerOpt)
+	opts.Narrator = resolveNarrator(opts.Narrator, opts.Strict)
+
+	// VOICEPEAK and the player run in their own process group, out of reach
+	// of Ctrl-C, so an interrupt cancels ctx to stop them.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *linesOpt {
+		if *dirOpt != "" {
+			log.Fatalf("Error: -lines cannot be combined with -d")
+		}
+		r, err := openInput(input)
+		if err != nil {
 			log.Fatalf("Error: %v", err)
 		}
+		defer r.Close()
+
+		if input == "-" && !stdinIsPiped() {
+			fmt.Fprintln(os.Stderr, "Reading lines from the terminal; end with Ctrl-D (Ctrl-Z then Enter on Windows).")
+		}
+		if err := speakLines(ctx, r, opts, *timeoutOpt); err != nil {
+			fatalSpeakError(err, *timeoutOpt)
+		}
+		return
+	}
+
+	if *timeoutOpt > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeoutOpt)
+		defer cancel()
+	}
+
+	if *dirOpt == "" {
+		text := flagSet.Arg(0)
+		if input != "" {
+			var err error
+			if text, err = readInput(input); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+		}
+		if toStdout {
+			if err := vpeak.SynthesizeToContext(ctx, os.Stdout, text, opts); err != nil {
+				fatalSpeakError(err, *timeoutOpt)
+			}
+			return
+		}
+		if err := vpeak.GenerateSpeechContext(ctx, text, opts); err != nil {
+			fatalSpeakError(err, *timeoutOpt)
+		}
 	} else {
-		if err := vpeak.ProcessTextFiles(*dirOpt, opts); err != nil {
-			log.Fatalf("Error: %v", err)
+		result, err := vpeak.ProcessTextFilesContext(ctx, *dirOpt, opts)
+		if err != nil {
+			fatalSpeakError(err, *timeoutOpt)
 		}
+		reportBatch(result)
 	}
 
 	fmt.Println("Commands executed successfully")
 }
 
+func runScriptCommand(args []string) {
+	flagSet := flag.NewFlagSet("script", flag.ExitOnError)
+	outputOpt := flagSet.String("o", "", "Output file path")
+	pauseOpt := flagSet.Duration("pause", 300*time.Millisecond, "Silence inserted between lines")
+	maxChunkOpt := flagSet.Int("max-chunk", vpeak.DefaultMaxChunkLength, "Maximum characters per VOICEPEAK call; longer lines are split and joined")
+	silentOpt := flagSet.Bool("silent", false, "Silent mode (no sound)")
+	vpPathOpt := flagSet.String("voicepeak-path", "", voicepeakPathUsage)
+	playerOpt := flagSet.String("player", "", "Audio player: aplay, paplay, pw-play, ffplay, mpv or a command line (default $VPEAK_PLAYER or auto-detect)")
+	timeoutOpt := flagSet.Duration("timeout", 0, "Abort if rendering and playback do not finish within this duration (e.g. 2m, 0 disables)")
+	noCacheOpt := flagSet.Bool("no-cache", false, "Always run VOICEPEAK instead of reusing cached audio")
+	strictOpt := flagSet.Bool("strict", false, "Check every line's narrator and emotion against those VOICEPEAK lists before rendering it")
+	flagSet.Usage = func() {
+		fmt.Printf("Usage: %s script [OPTIONS] <file>\n", os.Args[0])
+		fmt.Println("Options:")
+		flagSet.PrintDefaults()
+		fmt.Println("\nScript format (one line per utterance, # starts a comment):")
+		fmt.Println("  f1: こんにちは")
+		fmt.Println("  m1[happy=50,speed=120]: やあ")
+		fmt.Println("  Zundamon[pitch=-20,pause=1s]: よろしくなのだ")
+		fmt.Println("\nModifiers: speed=50-200, pitch=-300-300, pause=<duration after the line>, other names are emotions.")
+	}
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	useConfig()
+
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+	if *pauseOpt < 0 {
+		log.Fatalf("Pause must not be negative")
+	}
+
+	script, err := vpeak.ParseScriptFile(flagSet.Arg(0))
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	useVoicepeak(*vpPathOpt, true)
+	useCache(*noCacheOpt)
+	useCatalog()
+	usePlayer(*playerOpt)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *timeoutOpt > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeoutOpt)
+		defer cancel()
+	}
+
+	opts := vpeak.Options{
+		Output:         *outputOpt,
+		Silent:         *silentOpt,
+		MaxChunkLength: *maxChunkOpt,
+		Strict:         *strictOpt,
+	}
+	if err := vpeak.RenderScript(ctx, script, opts, *pauseOpt); err != nil {
+		fatalSpeakError(err, *timeoutOpt)
+	}
+
+	fmt.Println("Script rendered successfully")
+}
+
+func runCacheCommand(args []string) {
+	flagSet := flag.NewFlagSet("cache", flag.ExitOnError)
+	flagSet.Usage = func() {
+		fmt.Printf("Usage: %s cache <command>\n", os.Args[0])
+		fmt.Println("Commands:")
+		fmt.Println("  stats  Print the number and size of cached audio files")
+		fmt.Println("  clear  Delete all cached audio files and the narrator catalog")
+	}
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	dir, err := vpeak.DefaultCacheDir()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	cache := vpeak.NewCache(dir)
+
+	switch flagSet.Arg(0) {
+	case "stats":
+		stats, err := cache.Stats()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		fmt.Printf("Directory: %s\n", stats.Dir)
+		fmt.Printf("Entries:   %d\n", stats.Entries)
+		fmt.Printf("Size:      %.1f MiB (limit %d MiB)\n", float64(stats.Size)/(1<<20), cache.MaxSize>>20)
+		if stats.Entries > 0 {
+			fmt.Printf("Oldest:    %s\n", stats.Oldest.Format(time.RFC3339))
+			fmt.Printf("Newest:    %s\n", stats.Newest.Format(time.RFC3339))
+		}
+	case "clear":
+		if err := cache.Clear(); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if path, err := vpeak.DefaultCatalogPath(); err == nil {
+			if err := vpeak.NewCatalog(path).Clear(); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+		}
+		fmt.Println("Cache cleared successfully")
+	default:
+		flagSet.Usage()
+		os.Exit(1)
+	}
+}
+
+// useConfig loads the config file and registers its narrator aliases.
+func useConfig() *vpeak.Config {
+	config, err := vpeak.LoadDefaultConfig()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if err := config.AddNarratorAliases(); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	return config
+}
+
+// printNarratorAliases lists the narrator aliases for the usage text,
+// including those of the config file if it can be loaded.
+func printNarratorAliases() {
+	if config, err := vpeak.LoadDefaultConfig(); err == nil {
+		_ = config.AddNarratorAliases()
+	}
+
+	aliases := vpeak.NarratorAliases()
+	width := 0
+	for _, a := range aliases {
+		if len(a.Alias) > width {
+			width = len(a.Alias)
+		}
+	}
+	for _, a := range aliases {
+		fmt.Printf("  %-*s %s\n", width+1, a.Alias+":", a.Narrator)
+	}
+}
+
+// resolveNarrator expands name to the installed narrator it refers to.
+// Aliases and the narrators they name are known without asking VOICEPEAK.
+// A name that matches no installed narrator exits with suggestions if strict
+// is set; otherwise it is passed to VOICEPEAK as it is, with a warning, as
+// is any name when the narrators cannot be listed.
+func resolveNarrator(name string, strict bool) string {
+	if name == "" {
+		return name
+	}
+	for _, a := range vpeak.NarratorAliases() {
+		if name == a.Alias || name == a.Narrator {
+			return name
+		}
+	}
+
+	narrator, err := vpeak.ResolveNarrator(context.Background(), name)
+	if err != nil {
+		if errors.Is(err, vpeak.ErrUnknownNarrator) {
+			if strict {
+				log.Fatalf("Error: %v", err)
+			}
+			log.Printf("Warning: %v; passing it to VOICEPEAK as is", err)
+		}
+		return name
+	}
+	return narrator
+}
+
+// applyProfile fills the options whose flags were not given on the command
+// line from the config defaults and the named profile.
+func applyProfile(flagSet *flag.FlagSet, opts *vpeak.Options, config *vpeak.Config, name string) {
+	profile, err := config.Profile(name)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	set := map[string]bool{}
+	flagSet.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if !set["n"] && profile.Narrator != "" {
+		opts.Narrator = profile.Narrator
+	}
+	if !set["e"] && profile.Emotion != "" {
+		opts.Emotion = profile.Emotion
+	}
+	if !set["speed"] && profile.Speed != nil {
+		opts.Speed = profile.Speed
+	}
+	if !set["pitch"] && profile.Pitch != nil {
+		opts.Pitch = profile.Pitch
+	}
+	if !set["max-chunk"] && profile.MaxChunkLength != 0 {
+		opts.MaxChunkLength = profile.MaxChunkLength
+	}
+	if !set["chunk-pause"] && profile.ChunkPause != 0 {
+		opts.ChunkPause = profile.ChunkPause
+	}
+	if !set["heading-pause"] && profile.HeadingPause != 0 {
+		opts.HeadingPause = profile.HeadingPause
+	}
+	if !set["subtitles"] && profile.Subtitles != "" {
+		opts.Subtitles = profile.Subtitles
+	}
+	if !set["format"] && profile.Format != "" {
+		opts.Format = profile.Format
+	}
+}
+
+// useVoicepeak points DefaultClient at the VOICEPEAK found by
+// vpeak.FindVoicepeak. If none is found, it exits when required and warns
+// otherwise.
+func useVoicepeak(path string, required bool) {
+	installation, err := vpeak.FindVoicepeak(path)
+	if err != nil {
+		if required {
+			log.Fatalf("Error: %v", err)
+		}
+		log.Printf("Warning: %v", err)
+		return
+	}
+	installation.Configure(vpeak.DefaultClient)
+}
+
+// usePlayer selects the audio player named by spec, if any.
+func usePlayer(spec string) {
+	if spec == "" {
+		return
+	}
+
+	player, err := vpeak.NewPlayer(spec)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	vpeak.DefaultClient.Player = player
+}
+
+// useCache enables the on-disk synthesis cache unless disabled.
+func useCache(disabled bool) {
+	if disabled {
+		return
+	}
+
+	dir, err := vpeak.DefaultCacheDir()
+	if err != nil {
+		return
+	}
+	vpeak.DefaultClient.Cache = vpeak.NewCache(dir)
+}
+
+// useCatalog keeps the narrators and emotions VOICEPEAK lists in the cache
+// directory.
+func useCatalog() {
+	path, err := vpeak.DefaultCatalogPath()
+	if err != nil {
+		return
+	}
+	vpeak.DefaultClient.Catalog = vpeak.NewCatalog(path)
+}
+
+func runServeCommand(args []string) {
+	flagSet := flag.NewFlagSet("serve", flag.ExitOnError)
+	addrOpt := flagSet.String("addr", ":8080", "Address to listen on")
+	fileOpt := flagSet.String("dict-file", "", "Dictionary file path (defaults to VOICEPEAK's dictionary)")
+	compatOpt := flagSet.String("compat", "", "Serve a compatible API instead of vpeak's own (voicevox)")
+	vpPathOpt := flagSet.String("voicepeak-path", "", voicepeakPathUsage)
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	useConfig()
+	useVoicepeak(*vpPathOpt, false)
+
+	var handler http.Handler
+	switch *compatOpt {
+	case "":
+		handler = server.New(vpeak.DefaultClient, *fileOpt)
+	case "voicevox":
+		handler = server.NewVoicevox(vpeak.DefaultClient, *fileOpt)
+	default:
+		log.Fatalf("Error: unknown compat mode: %s", *compatOpt)
+	}
+
+	listenAndServe(*addrOpt, handler)
+}
+
+func runOpenAIServeCommand(args []string) {
+	flagSet := flag.NewFlagSet("openai-serve", flag.ExitOnError)
+	addrOpt := flagSet.String("addr", ":8000", "Address to listen on")
+	vpPathOpt := flagSet.String("voicepeak-path", "", voicepeakPathUsage)
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	useConfig()
+	useVoicepeak(*vpPathOpt, false)
+
+	listenAndServe(*addrOpt, server.NewOpenAI(vpeak.DefaultClient))
+}
+
+// listenAndServe serves handler on addr until interrupted.
+func listenAndServe(addr string, handler http.Handler) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	httpServer := &http.Server{
+		Addr:    addr,
+		Handler: handler,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Listening on %s", addr)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+// listFlag is a flag that may be repeated and holds comma-separated values.
+type listFlag []string
+
+func (f *listFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *listFlag) Set(value string) error {
+	*f = append(*f, splitList(value)...)
+	return nil
+}
+
+func splitList(s string) []string {
+	var values []string
+	for _, value := range strings.Split(s, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// reportBatch prints failed files and exits with an error if there are any.
+func reportBatch(result *vpeak.BatchResult) {
+	failed := result.Failed()
+	for _, file := range failed {
+		log.Printf("Error generating speech for file (%s): %v", file.Input, file.Err)
+	}
+	if len(failed) > 0 {
+		log.Fatalf("Error: %d of %d files failed", len(failed), len(result.Files))
+	}
+	if skipped := result.Skipped(); skipped > 0 {
+		fmt.Printf("Skipped %d of %d files that are up to date\n", skipped, len(result.Files))
+	}
+}
+
+func fatalSpeakError(err error, timeout time.Duration) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		log.Fatalf("Error: timed out after %s", timeout)
+	}
+	if errors.Is(err, context.Canceled) {
+		log.Fatalf("Error: interrupted")
+	}
+	log.Fatalf("Error: %v", err)
+}
+
 func runDictCommand(args []string) {
 	if len(args) == 0 {
 		printDictUsage()
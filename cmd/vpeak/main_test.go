@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// narratorEngine lists two installed narrators.
+type narratorEngine struct{}
+
+func (narratorEngine) Run(ctx context.Context, args []string) ([]byte, error) {
+	return []byte("Japanese Female 1\n夏色花梨\n"), nil
+}
+
+func TestResolveNarrator(t *testing.T) {
+	useEngine(t, narratorEngine{})
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "f1", want: "f1"},
+		{name: "夏色", want: "夏色花梨"},
+		// Without -strict an unknown name is left to VOICEPEAK.
+		{name: "宮舞モカ", want: "宮舞モカ"},
+	}
+
+	for _, tt := range tests {
+		if got := resolveNarrator(tt.name, false); got != tt.want {
+			t.Errorf("resolveNarrator(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/shinshin86/vpeak"
+)
+
+// narratorInfo is a narrator as printed by the narrators command.
+type narratorInfo struct {
+	Narrator string   `json:"narrator"`
+	Aliases  []string `json:"aliases"`
+}
+
+// voiceInfo is a narrator as printed by the voices command.
+type voiceInfo struct {
+	Narrator string   `json:"narrator"`
+	Aliases  []string `json:"aliases"`
+	Emotions []string `json:"emotions"`
+}
+
+func runNarratorsCommand(args []string) {
+	flagSet := flag.NewFlagSet("narrators", flag.ExitOnError)
+	jsonOpt := flagSet.Bool("json", false, "Print JSON instead of a table")
+	vpPathOpt := flagSet.String("voicepeak-path", "", voicepeakPathUsage)
+	flagSet.Usage = func() {
+		fmt.Printf("Usage: %s narrators [-json]\n", os.Args[0])
+		fmt.Println("List the installed narrators and their aliases.")
+		fmt.Println("Options:")
+		flagSet.PrintDefaults()
+	}
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	useConfig()
+	useVoicepeak(*vpPathOpt, true)
+
+	narrators, err := vpeak.ListNarrators()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	aliases := aliasesByNarrator()
+	infos := make([]narratorInfo, 0, len(narrators))
+	for _, narrator := range narrators {
+		infos = append(infos, narratorInfo{Narrator: narrator, Aliases: nonNil(aliases[narrator])})
+	}
+
+	if err := writeNarrators(os.Stdout, infos, *jsonOpt); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+func runEmotionsCommand(args []string) {
+	flagSet := flag.NewFlagSet("emotions", flag.ExitOnError)
+	jsonOpt := flagSet.Bool("json", false, "Print JSON instead of a table")
+	vpPathOpt := flagSet.String("voicepeak-path", "", voicepeakPathUsage)
+	flagSet.Usage = func() {
+		fmt.Printf("Usage: %s emotions [-json] <narrator>\n", os.Args[0])
+		fmt.Println("List the emotions of a narrator, given by name, alias or unique prefix.")
+		fmt.Println("Options:")
+		flagSet.PrintDefaults()
+	}
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	useConfig()
+	useVoicepeak(*vpPathOpt, true)
+
+	narrator, err := vpeak.ResolveNarrator(context.Background(), flagSet.Arg(0))
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	emotions, err := vpeak.ListEmotions(narrator)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	if err := writeEmotions(os.Stdout, vpeak.Voice{Narrator: narrator, Emotions: nonNil(emotions)}, *jsonOpt); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+func runVoicesCommand(args []string) {
+	flagSet := flag.NewFlagSet("voices", flag.ExitOnError)
+	allOpt := flagSet.Bool("all", false, "List every installed narrator")
+	jsonOpt := flagSet.Bool("json", false, "Print JSON instead of a table")
+	vpPathOpt := flagSet.String("voicepeak-path", "", voicepeakPathUsage)
+	flagSet.Usage = func() {
+		fmt.Printf("Usage: %s voices [-json] -all\n", os.Args[0])
+		fmt.Printf("       %s voices [-json] <narrator>...\n", os.Args[0])
+		fmt.Println("List narrators with their aliases and emotions.")
+		fmt.Println("Options:")
+		flagSet.PrintDefaults()
+	}
+	if err := flagSet.Parse(args); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if *allOpt == (flagSet.NArg() > 0) {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	useConfig()
+	useVoicepeak(*vpPathOpt, true)
+
+	var voices []vpeak.Voice
+	if *allOpt {
+		var err error
+		if voices, err = vpeak.ListVoices(); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	} else {
+		for _, name := range flagSet.Args() {
+			narrator, err := vpeak.ResolveNarrator(context.Background(), name)
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			emotions, err := vpeak.ListEmotions(narrator)
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			voices = append(voices, vpeak.Voice{Narrator: narrator, Emotions: emotions})
+		}
+	}
+
+	aliases := aliasesByNarrator()
+	infos := make([]voiceInfo, 0, len(voices))
+	for _, voice := range voices {
+		infos = append(infos, voiceInfo{
+			Narrator: voice.Narrator,
+			Aliases:  nonNil(aliases[voice.Narrator]),
+			Emotions: nonNil(voice.Emotions),
+		})
+	}
+
+	if err := writeVoices(os.Stdout, infos, *jsonOpt); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+// writeNarrators writes infos to w as a table, or as JSON if asJSON is set.
+func writeNarrators(w io.Writer, infos []narratorInfo, asJSON bool) error {
+	if asJSON {
+		return writeJSON(w, infos)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NARRATOR\tALIASES")
+	for _, info := range infos {
+		fmt.Fprintf(tw, "%s\t%s\n", info.Narrator, strings.Join(info.Aliases, ", "))
+	}
+	return tw.Flush()
+}
+
+// writeEmotions writes the emotions of voice to w one per line, or voice as
+// JSON if asJSON is set.
+func writeEmotions(w io.Writer, voice vpeak.Voice, asJSON bool) error {
+	if asJSON {
+		return writeJSON(w, voice)
+	}
+
+	for _, emotion := range voice.Emotions {
+		if _, err := fmt.Fprintln(w, emotion); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeVoices writes infos to w as a table, or as JSON if asJSON is set.
+func writeVoices(w io.Writer, infos []voiceInfo, asJSON bool) error {
+	if asJSON {
+		return writeJSON(w, infos)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NARRATOR\tALIASES\tEMOTIONS")
+	for _, info := range infos {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", info.Narrator, strings.Join(info.Aliases, ", "), strings.Join(info.Emotions, ", "))
+	}
+	return tw.Flush()
+}
+
+// aliasesByNarrator returns the registered aliases of each narrator.
+func aliasesByNarrator() map[string][]string {
+	aliases := map[string][]string{}
+	for _, a := range vpeak.NarratorAliases() {
+		aliases[a.Narrator] = append(aliases[a.Narrator], a.Alias)
+	}
+	return aliases
+}
+
+func writeJSON(w io.Writer, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+func nonNil(items []string) []string {
+	if items == nil {
+		return []string{}
+	}
+	return items
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/shinshin86/vpeak"
+)
+
+func TestWriteNarrators(t *testing.T) {
+	infos := []narratorInfo{
+		{Narrator: "Japanese Female 1", Aliases: []string{"f1"}},
+		{Narrator: "夏色花梨", Aliases: []string{}},
+	}
+
+	var buf bytes.Buffer
+	if err := writeNarrators(&buf, infos, false); err != nil {
+		t.Fatalf("writeNarrators() error = %v", err)
+	}
+	want := "NARRATOR           ALIASES\n" +
+		"Japanese Female 1  f1\n" +
+		"夏色花梨               \n"
+	if got := buf.String(); got != want {
+		t.Fatalf("writeNarrators() = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	if err := writeNarrators(&buf, infos, true); err != nil {
+		t.Fatalf("writeNarrators(json) error = %v", err)
+	}
+	var decoded []narratorInfo
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, infos) {
+		t.Fatalf("writeNarrators(json) = %+v, want %+v", decoded, infos)
+	}
+}
+
+func TestWriteEmotions(t *testing.T) {
+	voice := vpeak.Voice{Narrator: "Japanese Female 1", Emotions: []string{"happy", "fun"}}
+
+	var buf bytes.Buffer
+	if err := writeEmotions(&buf, voice, false); err != nil {
+		t.Fatalf("writeEmotions() error = %v", err)
+	}
+	if got, want := buf.String(), "happy\nfun\n"; got != want {
+		t.Fatalf("writeEmotions() = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	if err := writeEmotions(&buf, voice, true); err != nil {
+		t.Fatalf("writeEmotions(json) error = %v", err)
+	}
+	var decoded vpeak.Voice
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, voice) {
+		t.Fatalf("writeEmotions(json) = %+v, want %+v", decoded, voice)
+	}
+}
+
+func TestWriteVoices(t *testing.T) {
+	infos := []voiceInfo{
+		{Narrator: "Japanese Male 1", Aliases: []string{"m1"}, Emotions: []string{"happy", "sad"}},
+		{Narrator: "Japanese Female Child", Aliases: []string{"c"}, Emotions: []string{}},
+	}
+
+	var buf bytes.Buffer
+	if err := writeVoices(&buf, infos, false); err != nil {
+		t.Fatalf("writeVoices() error = %v", err)
+	}
+	want := "NARRATOR               ALIASES  EMOTIONS\n" +
+		"Japanese Male 1        m1       happy, sad\n" +
+		"Japanese Female Child  c        \n"
+	if got := buf.String(); got != want {
+		t.Fatalf("writeVoices() = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	if err := writeVoices(&buf, infos, true); err != nil {
+		t.Fatalf("writeVoices(json) error = %v", err)
+	}
+	var decoded []voiceInfo
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, infos) {
+		t.Fatalf("writeVoices(json) = %+v, want %+v", decoded, infos)
+	}
+}
@@ -0,0 +1,278 @@
+package vpeak
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ConfigEnv is the environment variable that overrides the config file path.
+const ConfigEnv = "VPEAK_CONFIG"
+
+// ProjectConfigName is the project config file, read from the current
+// directory, whose settings override the user config file.
+const ProjectConfigName = ".vpeak.json"
+
+var ErrProfileNotFound = errors.New("profile not found")
+
+// Config is the vpeak configuration file, a JSON object such as
+//
+//	{
+//	  "voicepeak_path": "/opt/voicepeak/voicepeak",
+//	  "wine_prefix": "/home/me/.wine-voicepeak",
+//	  "narrator_aliases": {"moka": "宮舞モカ"},
+//	  "defaults": {"narrator": "f1"},
+//	  "profiles": {
+//	    "news": {"narrator": "m1", "speed": 110},
+//	    "mascot": {"narrator": "c", "emotion": "happy=80", "pitch": 50}
+//	  }
+//	}
+type Config struct {
+	// VoicepeakPath is the VOICEPEAK executable. A .exe path is run through
+	// Wine on systems other than Windows.
+	VoicepeakPath string `json:"voicepeak_path,omitempty"`
+	// Wine is the Wine launcher command. If empty, "wine" is used.
+	Wine string `json:"wine,omitempty"`
+	// WinePrefix is the WINEPREFIX VOICEPEAK is installed in. If empty,
+	// $WINEPREFIX or Wine's default prefix is used.
+	WinePrefix string `json:"wine_prefix,omitempty"`
+
+	// NarratorAliases maps short names to narrator names. See
+	// AddNarratorAlias.
+	NarratorAliases map[string]string `json:"narrator_aliases,omitempty"`
+
+	// Defaults are the options used when no profile sets them.
+	Defaults Profile `json:"defaults,omitempty"`
+	// Profiles are named sets of options, selected with Options.Profile.
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+}
+
+// Profile is a named set of options in the config file. Empty fields are
+// left to the defaults.
+type Profile struct {
+	Narrator       string `json:"narrator,omitempty"`
+	Emotion        string `json:"emotion,omitempty"`
+	Speed          *int   `json:"speed,omitempty"`
+	Pitch          *int   `json:"pitch,omitempty"`
+	MaxChunkLength int    `json:"max_chunk_length,omitempty"`
+	// ChunkPause and HeadingPause are durations such as "300ms".
+	ChunkPause   string `json:"chunk_pause,omitempty"`
+	HeadingPause string `json:"heading_pause,omitempty"`
+	Subtitles    string `json:"subtitles,omitempty"`
+	Format       string `json:"format,omitempty"`
+}
+
+// merge returns p with its empty fields taken from base.
+func (p Profile) merge(base Profile) Profile {
+	if p.Narrator == "" {
+		p.Narrator = base.Narrator
+	}
+	if p.Emotion == "" {
+		p.Emotion = base.Emotion
+	}
+	if p.Speed == nil {
+		p.Speed = base.Speed
+	}
+	if p.Pitch == nil {
+		p.Pitch = base.Pitch
+	}
+	if p.MaxChunkLength == 0 {
+		p.MaxChunkLength = base.MaxChunkLength
+	}
+	if p.ChunkPause == "" {
+		p.ChunkPause = base.ChunkPause
+	}
+	if p.HeadingPause == "" {
+		p.HeadingPause = base.HeadingPause
+	}
+	if p.Subtitles == "" {
+		p.Subtitles = base.Subtitles
+	}
+	if p.Format == "" {
+		p.Format = base.Format
+	}
+	return p
+}
+
+// Options converts the profile into Options.
+func (p Profile) Options() (Options, error) {
+	opts := Options{
+		Narrator:       p.Narrator,
+		Emotion:        p.Emotion,
+		Speed:          p.Speed,
+		Pitch:          p.Pitch,
+		MaxChunkLength: p.MaxChunkLength,
+		Subtitles:      p.Subtitles,
+		Format:         p.Format,
+	}
+
+	var err error
+	if p.ChunkPause != "" {
+		if opts.ChunkPause, err = time.ParseDuration(p.ChunkPause); err != nil {
+			return Options{}, fmt.Errorf("invalid chunk_pause: %w", err)
+		}
+	}
+	if p.HeadingPause != "" {
+		if opts.HeadingPause, err = time.ParseDuration(p.HeadingPause); err != nil {
+			return Options{}, fmt.Errorf("invalid heading_pause: %w", err)
+		}
+	}
+	return opts, nil
+}
+
+// Profile returns the named profile merged over the defaults. An empty name
+// returns the defaults.
+func (c *Config) Profile(name string) (Options, error) {
+	profile := c.Defaults
+	if name != "" {
+		named, ok := c.Profiles[name]
+		if !ok {
+			return Options{}, fmt.Errorf("%w: %q", ErrProfileNotFound, name)
+		}
+		profile = named.merge(c.Defaults)
+	}
+
+	opts, err := profile.Options()
+	if err != nil {
+		return Options{}, fmt.Errorf("profile %q: %w", name, err)
+	}
+	return opts, nil
+}
+
+// AddNarratorAliases registers the narrator aliases of the config with
+// AddNarratorAlias.
+func (c *Config) AddNarratorAliases() error {
+	aliases := make([]string, 0, len(c.NarratorAliases))
+	for alias := range c.NarratorAliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	for _, alias := range aliases {
+		if err := AddNarratorAlias(alias, c.NarratorAliases[alias]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// merge returns c with the settings of override applied on top.
+func (c *Config) merge(override *Config) *Config {
+	merged := *c
+	if override.VoicepeakPath != "" {
+		merged.VoicepeakPath = override.VoicepeakPath
+	}
+	if override.Wine != "" {
+		merged.Wine = override.Wine
+	}
+	if override.WinePrefix != "" {
+		merged.WinePrefix = override.WinePrefix
+	}
+	merged.Defaults = override.Defaults.merge(c.Defaults)
+
+	merged.NarratorAliases = map[string]string{}
+	for alias, narrator := range c.NarratorAliases {
+		merged.NarratorAliases[alias] = narrator
+	}
+	for alias, narrator := range override.NarratorAliases {
+		merged.NarratorAliases[alias] = narrator
+	}
+
+	merged.Profiles = map[string]Profile{}
+	for name, profile := range c.Profiles {
+		merged.Profiles[name] = profile
+	}
+	for name, profile := range override.Profiles {
+		merged.Profiles[name] = profile.merge(c.Profiles[name])
+	}
+	return &merged
+}
+
+// DefaultConfigPath returns $VPEAK_CONFIG if set, and otherwise config.json
+// in the vpeak directory under the user config directory, e.g.
+// ~/.config/vpeak/config.json on Linux.
+func DefaultConfigPath() (string, error) {
+	if path := os.Getenv(ConfigEnv); path != "" {
+		return path, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve config directory: %w", err)
+	}
+	return filepath.Join(dir, "vpeak", "config.json"), nil
+}
+
+// LoadConfig reads the config file at path. A missing file yields an empty
+// config.
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return config, nil
+		}
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// LoadDefaultConfig reads the config file at DefaultConfigPath, overridden
+// by ProjectConfigName in the current directory if it exists.
+func LoadDefaultConfig() (*Config, error) {
+	path, err := DefaultConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := LoadConfig(ProjectConfigName)
+	if err != nil {
+		return nil, err
+	}
+	return config.merge(project), nil
+}
+
+// LoadProfile returns the named profile of the default config merged over
+// its defaults. An empty name returns the defaults.
+func LoadProfile(name string) (Options, error) {
+	config, err := LoadDefaultConfig()
+	if err != nil {
+		return Options{}, err
+	}
+	return config.Profile(name)
+}
+
+// resolveProfile fills the fields opts leaves empty from opts.Profile. The
+// narrator aliases of the config are registered too, as profiles may use
+// them.
+func resolveProfile(opts Options) (Options, error) {
+	if opts.Profile == "" {
+		return opts, nil
+	}
+
+	config, err := LoadDefaultConfig()
+	if err != nil {
+		return Options{}, err
+	}
+	if err := config.AddNarratorAliases(); err != nil {
+		return Options{}, err
+	}
+	profile, err := config.Profile(opts.Profile)
+	if err != nil {
+		return Options{}, err
+	}
+	opts.Profile = ""
+	return opts.withDefaults(profile), nil
+}
@@ -0,0 +1,108 @@
+package vpeak
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// useConfig points the default config at user and runs the test in a
+// directory containing project as .vpeak.json, if non-empty.
+func useConfig(t *testing.T, user, project string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	userPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(userPath, []byte(user), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	t.Setenv(ConfigEnv, userPath)
+
+	projectDir := filepath.Join(dir, "project")
+	if err := os.Mkdir(projectDir, 0o755); err != nil {
+		t.Fatalf("os.Mkdir() error = %v", err)
+	}
+	if project != "" {
+		if err := os.WriteFile(filepath.Join(projectDir, ProjectConfigName), []byte(project), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("os.Getwd() error = %v", err)
+	}
+	if err := os.Chdir(projectDir); err != nil {
+		t.Fatalf("os.Chdir() error = %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestLoadProfile(t *testing.T) {
+	useConfig(t, `{
+		"defaults": {"narrator": "f1", "chunk_pause": "200ms"},
+		"profiles": {
+			"news": {"narrator": "m1", "speed": 110},
+			"mascot": {"narrator": "c", "emotion": "happy=80", "pitch": 50}
+		}
+	}`, `{
+		"defaults": {"emotion": "fun"},
+		"profiles": {
+			"news": {"pitch": -20},
+			"local": {"heading_pause": "2s"}
+		}
+	}`)
+
+	speed, pitch, newsPitch := 110, 50, -20
+	tests := []struct {
+		name string
+		want Options
+	}{
+		{name: "", want: Options{Narrator: "f1", Emotion: "fun", ChunkPause: 200 * time.Millisecond}},
+		{name: "news", want: Options{Narrator: "m1", Emotion: "fun", Speed: &speed, Pitch: &newsPitch, ChunkPause: 200 * time.Millisecond}},
+		{name: "mascot", want: Options{Narrator: "c", Emotion: "happy=80", Pitch: &pitch, ChunkPause: 200 * time.Millisecond}},
+		{name: "local", want: Options{Narrator: "f1", Emotion: "fun", ChunkPause: 200 * time.Millisecond, HeadingPause: 2 * time.Second}},
+	}
+
+	for _, tt := range tests {
+		got, err := LoadProfile(tt.name)
+		if err != nil {
+			t.Fatalf("LoadProfile(%q) error = %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LoadProfile(%q) = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := LoadProfile("missing"); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("LoadProfile(missing) error = %v, want %v", err, ErrProfileNotFound)
+	}
+}
+
+func TestLoadProfileInvalidDuration(t *testing.T) {
+	useConfig(t, `{"profiles": {"slow": {"chunk_pause": "long"}}}`, "")
+
+	if _, err := LoadProfile("slow"); err == nil {
+		t.Fatal("LoadProfile() error = nil, want invalid chunk_pause")
+	}
+}
+
+func TestClientGenerateSpeechProfile(t *testing.T) {
+	useConfig(t, `{"profiles": {"news": {"narrator": "m1", "speed": 110, "emotion": "happy"}}}`, "")
+
+	engine := &fakeEngine{}
+	client := &Client{Engine: engine}
+
+	err := client.GenerateSpeech("hi", Options{Profile: "news", Emotion: "sad", Output: "hi.wav", Silent: true})
+	if err != nil {
+		t.Fatalf("GenerateSpeech() error = %v", err)
+	}
+
+	want := [][]string{{"-o", "hi.wav", "--emotion", "sad=100", "--narrator", "Japanese Male 1", "-s", "hi", "--speed", "110"}}
+	if !reflect.DeepEqual(engine.calls, want) {
+		t.Fatalf("engine calls = %#v, want %#v", engine.calls, want)
+	}
+}
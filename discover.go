@@ -0,0 +1,188 @@
+package vpeak
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// VoicepeakPathEnv is the environment variable naming the VOICEPEAK
+// executable.
+const VoicepeakPathEnv = "VPEAK_VOICEPEAK_PATH"
+
+// Wine runs the Windows build of VOICEPEAK on other systems.
+type Wine struct {
+	// Command is the Wine launcher. If empty, "wine" is used.
+	Command string
+	// Prefix is the WINEPREFIX of the VOICEPEAK installation. If empty, the
+	// WINEPREFIX of the environment or Wine's default prefix is used.
+	Prefix string
+}
+
+func (w *Wine) command() string {
+	if w.Command != "" {
+		return w.Command
+	}
+	return "wine"
+}
+
+// prefix returns the Wine prefix directory in use.
+func (w *Wine) prefix() (string, error) {
+	if w.Prefix != "" {
+		return w.Prefix, nil
+	}
+	if prefix := os.Getenv("WINEPREFIX"); prefix != "" {
+		return prefix, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".wine"), nil
+}
+
+// environ returns env, or the current environment if env is nil, with
+// WINEPREFIX set to w.Prefix.
+func (w *Wine) environ(env []string) []string {
+	if w.Prefix == "" {
+		return env
+	}
+	if env == nil {
+		env = os.Environ()
+	}
+	return append(env[:len(env):len(env)], "WINEPREFIX="+w.Prefix)
+}
+
+// WindowsPath translates a Unix path into the Z: drive path under which
+// Wine exposes the Unix file system. Relative paths are resolved against dir,
+// or the current directory if dir is empty.
+func (w *Wine) WindowsPath(dir, path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		var err error
+		if path, err = filepath.Abs(filepath.Join(dir, path)); err != nil {
+			return "", err
+		}
+	}
+	return "Z:" + strings.ReplaceAll(filepath.ToSlash(path), "/", `\`), nil
+}
+
+// translateArgs rewrites the -o output path of VOICEPEAK arguments for Wine.
+func (w *Wine) translateArgs(dir string, args []string) ([]string, error) {
+	translated := append([]string(nil), args...)
+	for i := 0; i+1 < len(translated); i++ {
+		if translated[i] != "-o" {
+			continue
+		}
+		path, err := w.WindowsPath(dir, translated[i+1])
+		if err != nil {
+			return nil, err
+		}
+		translated[i+1] = path
+		i++
+	}
+	return translated, nil
+}
+
+// Installation is a VOICEPEAK executable found by FindVoicepeak.
+type Installation struct {
+	Path string
+	// Source tells where Path came from: "flag", VoicepeakPathEnv,
+	// "config", "PATH", "default" or "wine".
+	Source string
+	// Wine is set if Path must be run through Wine.
+	Wine *Wine
+}
+
+// Configure makes c run this installation.
+func (i *Installation) Configure(c *Client) {
+	c.Path = i.Path
+	c.Wine = i.Wine
+}
+
+// FindVoicepeak locates VOICEPEAK, trying in order path (e.g. from a
+// command-line flag), $VPEAK_VOICEPEAK_PATH, the config file, "voicepeak" in
+// PATH, the platform's default install location and, outside Windows, the
+// default location in the Wine prefix. A location that is set explicitly
+// but does not exist is an error rather than skipped. On systems other than
+// Windows a .exe is run through Wine.
+func FindVoicepeak(path string) (*Installation, error) {
+	config, err := LoadDefaultConfig()
+	if err != nil {
+		return nil, err
+	}
+	return findVoicepeak(path, config)
+}
+
+func findVoicepeak(path string, config *Config) (*Installation, error) {
+	wine := &Wine{Command: config.Wine, Prefix: config.WinePrefix}
+	install := func(path, source string) *Installation {
+		installation := &Installation{Path: path, Source: source}
+		if runtime.GOOS != "windows" && strings.EqualFold(filepath.Ext(path), ".exe") {
+			installation.Wine = wine
+		}
+		return installation
+	}
+
+	explicit := []struct{ path, source string }{
+		{path, "flag"},
+		{os.Getenv(VoicepeakPathEnv), VoicepeakPathEnv},
+		{config.VoicepeakPath, "config"},
+	}
+	for _, candidate := range explicit {
+		if candidate.path == "" {
+			continue
+		}
+		located, err := locateExecutable(candidate.path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s (from %s)", ErrVoicepeakNotFound, candidate.path, candidate.source)
+		}
+		return install(located, candidate.source), nil
+	}
+
+	if located, err := exec.LookPath("voicepeak"); err == nil {
+		return install(located, "PATH"), nil
+	}
+
+	if defaultPath := defaultVoicepeakPath(); defaultPath != "" {
+		if _, err := os.Stat(defaultPath); err == nil {
+			return install(defaultPath, "default"), nil
+		}
+	}
+
+	if runtime.GOOS != "windows" {
+		if prefix, err := wine.prefix(); err == nil {
+			winePath := filepath.Join(prefix, "drive_c", "Program Files", "VOICEPEAK", "voicepeak.exe")
+			if _, err := os.Stat(winePath); err == nil {
+				return install(winePath, "wine"), nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w: set %s, voicepeak_path in the config file or install VOICEPEAK in its default location", ErrVoicepeakNotFound, VoicepeakPathEnv)
+}
+
+// locateExecutable resolves a bare command name through PATH and checks that
+// a path exists.
+func locateExecutable(path string) (string, error) {
+	if !strings.ContainsAny(path, `/\`) {
+		return exec.LookPath(path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// defaultVoicepeakPath returns the platform's default install location.
+func defaultVoicepeakPath() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "/Applications/voicepeak.app/Contents/MacOS/voicepeak"
+	case "windows":
+		return "C:\\Program Files\\VOICEPEAK\\voicepeak.exe"
+	}
+	return ""
+}
@@ -0,0 +1,161 @@
+package vpeak
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writeExecutable(t *testing.T, path, script string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+}
+
+func TestFindVoicepeak(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses Unix paths")
+	}
+
+	dir := t.TempDir()
+	flagPath := filepath.Join(dir, "flag-voicepeak")
+	envPath := filepath.Join(dir, "env-voicepeak")
+	configPath := filepath.Join(dir, "voicepeak.exe")
+	pathDir := filepath.Join(dir, "bin")
+	if err := os.Mkdir(pathDir, 0o755); err != nil {
+		t.Fatalf("os.Mkdir() error = %v", err)
+	}
+	for _, path := range []string{flagPath, envPath, configPath, filepath.Join(pathDir, "voicepeak")} {
+		writeExecutable(t, path, "#!/bin/sh\n")
+	}
+	t.Setenv("PATH", pathDir)
+	t.Setenv("HOME", dir)
+	t.Setenv("WINEPREFIX", "")
+
+	config := &Config{VoicepeakPath: configPath, WinePrefix: "/prefix"}
+	tests := []struct {
+		name       string
+		flag       string
+		env        string
+		config     *Config
+		wantPath   string
+		wantSource string
+		wantWine   bool
+	}{
+		{name: "flag", flag: flagPath, env: envPath, config: config, wantPath: flagPath, wantSource: "flag"},
+		{name: "env", env: envPath, config: config, wantPath: envPath, wantSource: VoicepeakPathEnv},
+		{name: "config", config: config, wantPath: configPath, wantSource: "config", wantWine: true},
+		{name: "PATH", config: &Config{}, wantPath: filepath.Join(pathDir, "voicepeak"), wantSource: "PATH"},
+		{name: "flag command name", flag: "voicepeak", config: &Config{}, wantPath: filepath.Join(pathDir, "voicepeak"), wantSource: "flag"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(VoicepeakPathEnv, tt.env)
+
+			installation, err := findVoicepeak(tt.flag, tt.config)
+			if err != nil {
+				t.Fatalf("findVoicepeak() error = %v", err)
+			}
+			if installation.Path != tt.wantPath || installation.Source != tt.wantSource {
+				t.Fatalf("findVoicepeak() = %q from %q, want %q from %q", installation.Path, installation.Source, tt.wantPath, tt.wantSource)
+			}
+			if (installation.Wine != nil) != tt.wantWine {
+				t.Fatalf("Wine = %#v, want set = %v", installation.Wine, tt.wantWine)
+			}
+			if tt.wantWine && installation.Wine.Prefix != "/prefix" {
+				t.Fatalf("Wine.Prefix = %q, want /prefix", installation.Wine.Prefix)
+			}
+		})
+	}
+
+	t.Run("missing explicit path", func(t *testing.T) {
+		t.Setenv(VoicepeakPathEnv, filepath.Join(dir, "missing"))
+		if _, err := findVoicepeak("", config); !errors.Is(err, ErrVoicepeakNotFound) {
+			t.Fatalf("findVoicepeak() error = %v, want ErrVoicepeakNotFound", err)
+		}
+	})
+
+	t.Run("wine default", func(t *testing.T) {
+		if runtime.GOOS == "darwin" {
+			t.Skip("macOS has a native default location")
+		}
+		t.Setenv(VoicepeakPathEnv, "")
+		t.Setenv("PATH", "")
+
+		if _, err := findVoicepeak("", &Config{}); !errors.Is(err, ErrVoicepeakNotFound) {
+			t.Fatalf("findVoicepeak() error = %v, want ErrVoicepeakNotFound", err)
+		}
+
+		exe := filepath.Join(dir, ".wine", "drive_c", "Program Files", "VOICEPEAK", "voicepeak.exe")
+		if err := os.MkdirAll(filepath.Dir(exe), 0o755); err != nil {
+			t.Fatalf("os.MkdirAll() error = %v", err)
+		}
+		writeExecutable(t, exe, "")
+
+		installation, err := findVoicepeak("", &Config{})
+		if err != nil {
+			t.Fatalf("findVoicepeak() error = %v", err)
+		}
+		if installation.Path != exe || installation.Source != "wine" || installation.Wine == nil {
+			t.Fatalf("findVoicepeak() = %#v, want Wine installation at %s", installation, exe)
+		}
+	})
+}
+
+func TestWineWindowsPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses Unix paths")
+	}
+
+	wine := &Wine{}
+	tests := []struct {
+		dir, path, want string
+	}{
+		{path: "/home/me/out.wav", want: `Z:\home\me\out.wav`},
+		{dir: "/work", path: "audio/out.wav", want: `Z:\work\audio\out.wav`},
+	}
+	for _, tt := range tests {
+		got, err := wine.WindowsPath(tt.dir, tt.path)
+		if err != nil {
+			t.Fatalf("WindowsPath() error = %v", err)
+		}
+		if got != tt.want {
+			t.Fatalf("WindowsPath(%q, %q) = %q, want %q", tt.dir, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestClientRunsThroughWine(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	wine := filepath.Join(dir, "fake-wine")
+	writeExecutable(t, wine, "#!/bin/sh\nprintf '%s\\n' \"$WINEPREFIX\" \"$@\" > "+log+"\n")
+	exe := filepath.Join(dir, "voicepeak.exe")
+	writeExecutable(t, exe, "")
+
+	client := &Client{Path: exe, Dir: dir, Wine: &Wine{Command: wine, Prefix: "/prefix"}}
+	if err := client.GenerateSpeechContext(context.Background(), "hi", Options{Output: "out.wav", Silent: true}); err != nil {
+		t.Fatalf("GenerateSpeechContext() error = %v", err)
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	got := strings.Split(strings.TrimSpace(string(data)), "\n")
+	want := []string{"/prefix", exe, "-o", "Z:" + strings.ReplaceAll(filepath.Join(dir, "out.wav"), "/", `\`), "-s", "hi"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wine invocation = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,224 @@
+package vpeak_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/shinshin86/vpeak"
+	"github.com/shinshin86/vpeak/vpeaktest"
+	"github.com/shinshin86/vpeak/wav"
+)
+
+// These tests run the real exec path of vpeak against the fake VOICEPEAK.
+
+func TestMain(m *testing.M) {
+	vpeaktest.Main(m)
+}
+
+func TestGenerateSpeechWithFake(t *testing.T) {
+	fake := vpeaktest.New(t)
+	client := fake.Client()
+
+	output := filepath.Join(t.TempDir(), "long.wav")
+	speed := 120
+	opts := vpeak.Options{
+		Narrator:       "m1",
+		Emotion:        "happy=50",
+		Speed:          &speed,
+		Output:         output,
+		Silent:         true,
+		MaxChunkLength: 6,
+		Subtitles:      vpeak.SubtitleSRT,
+	}
+	if err := client.GenerateSpeech("おはよう。こんばんは。", opts); err != nil {
+		t.Fatalf("GenerateSpeech() error = %v", err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("Calls() = %q, want one call per sentence", calls)
+	}
+	wantTail := []string{"--emotion", "happy=50", "--narrator", "Japanese Male 1", "-s", "おはよう。", "--speed", "120"}
+	if got := calls[0][2:]; !reflect.DeepEqual(got, wantTail) {
+		t.Fatalf("first call = %q, want -o <chunk> %q", calls[0], wantTail)
+	}
+
+	clip, err := wav.ReadFile(output)
+	if err != nil {
+		t.Fatalf("wav.ReadFile() error = %v", err)
+	}
+	if got, want := clip.Duration().Milliseconds(), int64(1100); got != want {
+		t.Fatalf("Duration = %dms, want %dms", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(output), "long.srt")); err != nil {
+		t.Fatalf("subtitles not written: %v", err)
+	}
+}
+
+// statPlayer checks that every file it is asked to play exists.
+type statPlayer struct {
+	played []string
+}
+
+func (p *statPlayer) Play(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	p.played = append(p.played, path)
+	return nil
+}
+
+func TestGenerateSpeechWithFakeInDir(t *testing.T) {
+	fake := vpeaktest.New(t)
+	client := fake.Client()
+	client.Dir = t.TempDir()
+	player := &statPlayer{}
+	client.Player = player
+
+	// The default output is played from and removed in Dir.
+	if err := client.GenerateSpeech("こんにちは", vpeak.Options{}); err != nil {
+		t.Fatalf("GenerateSpeech() error = %v", err)
+	}
+	defaultOutput := filepath.Join(client.Dir, vpeak.WavName)
+	if !reflect.DeepEqual(player.played, []string{defaultOutput}) {
+		t.Fatalf("played = %q, want %q", player.played, defaultOutput)
+	}
+	if runtime.GOOS != "windows" {
+		if _, err := os.Stat(defaultOutput); !os.IsNotExist(err) {
+			t.Fatalf("default output after playing: %v, want it removed", err)
+		}
+	}
+
+	// Relative outputs land in Dir whether or not the text is split.
+	for _, text := range []string{"おはよう。", "おはよう。こんばんは。"} {
+		opts := vpeak.Options{Output: "x.wav", Silent: true, MaxChunkLength: 6, Subtitles: vpeak.SubtitleSRT}
+		if err := client.GenerateSpeech(text, opts); err != nil {
+			t.Fatalf("GenerateSpeech(%q) error = %v", text, err)
+		}
+		for _, name := range []string{"x.wav", "x.srt"} {
+			path := filepath.Join(client.Dir, name)
+			if _, err := os.Stat(path); err != nil {
+				t.Fatalf("GenerateSpeech(%q): %v", text, err)
+			}
+			os.Remove(path)
+		}
+	}
+
+	// Batches mirror their input tree into Dir.
+	input := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(input, "sub"), 0o755); err != nil {
+		t.Fatalf("os.MkdirAll() error = %v", err)
+	}
+	for _, name := range []string{"a.txt", "sub/b.txt"} {
+		if err := os.WriteFile(filepath.Join(input, name), []byte("あいう"), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+	}
+	result, err := client.ProcessTextFiles(input, vpeak.Options{Output: "out", Silent: true, Recursive: true})
+	if err != nil {
+		t.Fatalf("ProcessTextFiles() error = %v", err)
+	}
+	if result.Succeeded() != 2 {
+		t.Fatalf("result = %#v, want 2 succeeded", result.Files)
+	}
+	for _, name := range []string{"a.wav", "sub/b.wav"} {
+		if _, err := os.Stat(filepath.Join(client.Dir, "out", name)); err != nil {
+			t.Fatalf("ProcessTextFiles(): %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(client.Dir, "out", vpeak.ManifestName)); err != nil {
+		t.Fatalf("ProcessTextFiles(): %v", err)
+	}
+
+	// The manifest lets a resumed batch skip the files already in Dir.
+	result, err = client.ProcessTextFiles(input, vpeak.Options{Output: "out", Silent: true, Recursive: true, Resume: true})
+	if err != nil {
+		t.Fatalf("ProcessTextFiles(Resume) error = %v", err)
+	}
+	if result.Skipped() != 2 {
+		t.Fatalf("result = %#v, want 2 skipped", result.Files)
+	}
+}
+
+func TestListNarratorsAndEmotionsWithFake(t *testing.T) {
+	fake := vpeaktest.New(t)
+	fake.Noise()
+	client := fake.Client()
+
+	narrators, err := client.ListNarrators()
+	if err != nil {
+		t.Fatalf("ListNarrators() error = %v", err)
+	}
+	if !reflect.DeepEqual(narrators, vpeaktest.DefaultNarrators) {
+		t.Fatalf("ListNarrators() = %q, want %q", narrators, vpeaktest.DefaultNarrators)
+	}
+
+	emotions, err := client.ListEmotions("f2")
+	if err != nil {
+		t.Fatalf("ListEmotions() error = %v", err)
+	}
+	if !reflect.DeepEqual(emotions, vpeaktest.DefaultEmotions) {
+		t.Fatalf("ListEmotions() = %q, want %q", emotions, vpeaktest.DefaultEmotions)
+	}
+	if got := fake.Calls()[1]; !reflect.DeepEqual(got, []string{"--list-emotion", "Japanese Female 2"}) {
+		t.Fatalf("ListEmotions() ran %q", got)
+	}
+}
+
+func TestListVoicesWithFake(t *testing.T) {
+	fake := vpeaktest.New(t)
+	fake.Update(func(c *vpeaktest.Config) {
+		c.Narrators = []string{"宮舞モカ", "夏色花梨"}
+		c.Emotions = map[string][]string{"宮舞モカ": {"amaama", "live"}, "夏色花梨": {"hightension"}}
+	})
+
+	voices, err := fake.Client().ListVoices()
+	if err != nil {
+		t.Fatalf("ListVoices() error = %v", err)
+	}
+
+	want := []vpeak.Voice{
+		{Narrator: "宮舞モカ", Emotions: []string{"amaama", "live"}},
+		{Narrator: "夏色花梨", Emotions: []string{"hightension"}},
+	}
+	if !reflect.DeepEqual(voices, want) {
+		t.Fatalf("ListVoices() = %+v, want %+v", voices, want)
+	}
+	if calls := fake.Calls(); len(calls) != 3 {
+		t.Fatalf("Calls() = %q, want one list per narrator and the narrator list", calls)
+	}
+}
+
+func TestProcessTextFilesWithFake(t *testing.T) {
+	fake := vpeaktest.New(t)
+	fake.FailOn("broken")
+	client := fake.Client()
+
+	dir := t.TempDir()
+	for name, text := range map[string]string{"a.txt": "あいう", "b.txt": "broken", "c.txt": "かきくけこ"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+	}
+
+	out := t.TempDir()
+	result, err := client.ProcessTextFiles(dir, vpeak.Options{Output: out, Silent: true, Workers: 2})
+	if err != nil {
+		t.Fatalf("ProcessTextFiles() error = %v", err)
+	}
+	if result.Succeeded() != 2 || len(result.Failed()) != 1 {
+		t.Fatalf("result = %#v, want 2 succeeded and b.txt failed", result.Files)
+	}
+
+	clip, err := wav.ReadFile(filepath.Join(out, "c.wav"))
+	if err != nil {
+		t.Fatalf("wav.ReadFile() error = %v", err)
+	}
+	if got, want := clip.Duration().Milliseconds(), int64(500); got != want {
+		t.Fatalf("c.wav duration = %dms, want %dms", got, want)
+	}
+}
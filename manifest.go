@@ -0,0 +1,173 @@
+package vpeak
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ManifestName is the file, in the output directory, in which
+// ProcessTextFiles records completed files.
+const ManifestName = ".vpeak-manifest.json"
+
+// Manifest records the files a batch rendered successfully, keyed by their
+// path relative to the input directory.
+type Manifest struct {
+	Files map[string]ManifestEntry `json:"files"`
+}
+
+// ManifestEntry describes how an output file was rendered.
+type ManifestEntry struct {
+	// Hash is the SHA-256 of the input file's contents.
+	Hash      string          `json:"hash"`
+	Options   ManifestOptions `json:"options"`
+	Output    string          `json:"output"`
+	Completed time.Time       `json:"completed"`
+}
+
+// ManifestOptions are the Options that affect the rendered audio.
+type ManifestOptions struct {
+	Narrator       string        `json:"narrator,omitempty"`
+	Emotion        string        `json:"emotion,omitempty"`
+	Speed          *int          `json:"speed,omitempty"`
+	Pitch          *int          `json:"pitch,omitempty"`
+	MaxChunkLength int           `json:"max_chunk_length,omitempty"`
+	ChunkPause     time.Duration `json:"chunk_pause,omitempty"`
+	Subtitles      string        `json:"subtitles,omitempty"`
+	Format         string        `json:"format,omitempty"`
+	HeadingPause   time.Duration `json:"heading_pause,omitempty"`
+	SplitSections  bool          `json:"split_sections,omitempty"`
+}
+
+func manifestOptions(opts Options) ManifestOptions {
+	return ManifestOptions{
+		Narrator:       opts.Narrator,
+		Emotion:        opts.Emotion,
+		Speed:          opts.Speed,
+		Pitch:          opts.Pitch,
+		MaxChunkLength: opts.MaxChunkLength,
+		ChunkPause:     opts.ChunkPause,
+		Subtitles:      opts.Subtitles,
+		Format:         opts.Format,
+		HeadingPause:   opts.HeadingPause,
+		SplitSections:  opts.SplitSections,
+	}
+}
+
+func (o ManifestOptions) equal(other ManifestOptions) bool {
+	return o.Narrator == other.Narrator &&
+		o.Emotion == other.Emotion &&
+		equalIntPtr(o.Speed, other.Speed) &&
+		equalIntPtr(o.Pitch, other.Pitch) &&
+		o.MaxChunkLength == other.MaxChunkLength &&
+		o.ChunkPause == other.ChunkPause &&
+		o.Subtitles == other.Subtitles &&
+		o.Format == other.Format &&
+		o.HeadingPause == other.HeadingPause &&
+		o.SplitSections == other.SplitSections
+}
+
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// ReadManifest reads the manifest at path. A missing file yields an empty
+// manifest.
+func ReadManifest(path string) (*Manifest, error) {
+	manifest := &Manifest{Files: map[string]ManifestEntry{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return manifest, nil
+		}
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("parse manifest %s: %w", path, err)
+	}
+	if manifest.Files == nil {
+		manifest.Files = map[string]ManifestEntry{}
+	}
+	return manifest, nil
+}
+
+// WriteFile atomically replaces the manifest at path.
+func (m *Manifest) WriteFile(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	tempFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	tempPath := tempFile.Name()
+	defer os.Remove(tempPath)
+
+	_, err = tempFile.Write(append(data, '\n'))
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
+
+// UpToDate reports whether name was rendered from contents with the given
+// hash and options into output, and output still exists. For split Markdown
+// sections the first section's file must exist.
+func (m *Manifest) UpToDate(name, hash string, opts ManifestOptions, output string) bool {
+	entry, ok := m.Files[name]
+	if !ok || entry.Hash != hash || entry.Output != output || !entry.Options.equal(opts) {
+		return false
+	}
+	if opts.SplitSections && opts.Format == FormatMarkdown {
+		output = sectionOutput(output, 1)
+	}
+	_, err := os.Stat(output)
+	return err == nil
+}
+
+// manifestRecorder saves the manifest of a running batch after every
+// completed file, so that an interrupted batch can be resumed.
+type manifestRecorder struct {
+	mu       sync.Mutex
+	path     string
+	manifest *Manifest
+}
+
+func (r *manifestRecorder) upToDate(name, hash string, opts ManifestOptions, output string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.manifest.UpToDate(name, hash, opts, output)
+}
+
+func (r *manifestRecorder) record(name string, entry ManifestEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.manifest.Files[name] = entry
+	return r.manifest.WriteFile(r.path)
+}
+
+func hashContent(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
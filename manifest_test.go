@@ -0,0 +1,161 @@
+package vpeak
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClientProcessTextFilesResume(t *testing.T) {
+	dir := t.TempDir()
+	writeTextFiles(t, dir, map[string]string{"a.txt": "one", "b.txt": "two"})
+
+	engine := &fakeEngine{audio: testWAV(10)}
+	client := &Client{Engine: engine}
+	opts := Options{Silent: true, Resume: true}
+
+	if _, err := client.ProcessTextFiles(dir, opts); err != nil {
+		t.Fatalf("ProcessTextFiles() error = %v", err)
+	}
+	if len(engine.calls) != 2 {
+		t.Fatalf("engine calls = %d, want 2", len(engine.calls))
+	}
+
+	manifest, err := ReadManifest(filepath.Join(dir, ManifestName))
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	entry, ok := manifest.Files["a.txt"]
+	if !ok {
+		t.Fatalf("manifest files = %#v, want a.txt", manifest.Files)
+	}
+	if entry.Hash != hashContent([]byte("one")) || entry.Output != filepath.Join(dir, "a.wav") {
+		t.Fatalf("manifest entry = %#v", entry)
+	}
+
+	tests := []struct {
+		name      string
+		prepare   func()
+		opts      Options
+		wantCalls int
+	}{
+		{
+			name:      "unchanged",
+			prepare:   func() {},
+			opts:      opts,
+			wantCalls: 0,
+		},
+		{
+			name:      "without resume",
+			prepare:   func() {},
+			opts:      Options{Silent: true},
+			wantCalls: 2,
+		},
+		{
+			name:      "force",
+			prepare:   func() {},
+			opts:      Options{Silent: true, Resume: true, Force: true},
+			wantCalls: 2,
+		},
+		{
+			name:      "changed text",
+			prepare:   func() { writeTextFiles(t, dir, map[string]string{"a.txt": "uno"}) },
+			opts:      opts,
+			wantCalls: 1,
+		},
+		{
+			name:      "changed options",
+			prepare:   func() {},
+			opts:      Options{Silent: true, Resume: true, Narrator: "f1"},
+			wantCalls: 2,
+		},
+		{
+			name: "missing output",
+			prepare: func() {
+				if err := os.Remove(filepath.Join(dir, "b.wav")); err != nil {
+					t.Fatalf("os.Remove() error = %v", err)
+				}
+			},
+			opts:      Options{Silent: true, Resume: true, Narrator: "f1"},
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.prepare()
+			engine.calls = nil
+
+			result, err := client.ProcessTextFiles(dir, tt.opts)
+			if err != nil {
+				t.Fatalf("ProcessTextFiles() error = %v", err)
+			}
+			if len(engine.calls) != tt.wantCalls {
+				t.Fatalf("engine calls = %d, want %d", len(engine.calls), tt.wantCalls)
+			}
+			if want := len(result.Files) - tt.wantCalls; result.Skipped() != want {
+				t.Fatalf("Skipped() = %d, want %d", result.Skipped(), want)
+			}
+		})
+	}
+}
+
+func TestClientProcessTextFilesManifestOmitsFailures(t *testing.T) {
+	dir := t.TempDir()
+	writeTextFiles(t, dir, map[string]string{"a.txt": "one", "b.txt": "fail"})
+
+	client := &Client{Engine: &concurrencyEngine{}}
+	result, err := client.ProcessTextFiles(dir, Options{Silent: true})
+	if err != nil {
+		t.Fatalf("ProcessTextFiles() error = %v", err)
+	}
+	if len(result.Failed()) != 1 {
+		t.Fatalf("Failed() = %#v, want b.txt", result.Failed())
+	}
+
+	manifest, err := ReadManifest(filepath.Join(dir, ManifestName))
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	if _, ok := manifest.Files["a.txt"]; !ok {
+		t.Fatalf("manifest files = %#v, want a.txt", manifest.Files)
+	}
+	if _, ok := manifest.Files["b.txt"]; ok {
+		t.Fatalf("manifest files = %#v, want no b.txt", manifest.Files)
+	}
+}
+
+func TestClientProcessTextFilesCorruptManifest(t *testing.T) {
+	dir := t.TempDir()
+	writeTextFiles(t, dir, map[string]string{"a.txt": "one", ManifestName: "{"})
+
+	client := &Client{Engine: &concurrencyEngine{}}
+	if _, err := client.ProcessTextFiles(dir, Options{Silent: true, Resume: true}); err == nil {
+		t.Fatal("ProcessTextFiles(Resume) error = nil, want the manifest error")
+	}
+
+	result, err := client.ProcessTextFiles(dir, Options{Silent: true})
+	if err != nil {
+		t.Fatalf("ProcessTextFiles() error = %v", err)
+	}
+	if result.Succeeded() != 1 {
+		t.Fatalf("Succeeded() = %d, want 1", result.Succeeded())
+	}
+	manifest, err := ReadManifest(filepath.Join(dir, ManifestName))
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	if _, ok := manifest.Files["a.txt"]; !ok {
+		t.Fatalf("manifest files = %#v, want a.txt", manifest.Files)
+	}
+}
+
+func TestReadManifestMissing(t *testing.T) {
+	manifest, err := ReadManifest(filepath.Join(t.TempDir(), ManifestName))
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	if len(manifest.Files) != 0 {
+		t.Fatalf("manifest files = %#v, want none", manifest.Files)
+	}
+}
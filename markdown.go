@@ -0,0 +1,276 @@
+package vpeak
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shinshin86/vpeak/wav"
+)
+
+// Input formats accepted by Options.Format.
+const (
+	FormatText     = "text"
+	FormatMarkdown = "md"
+)
+
+var ErrInvalidFormat = errors.New("invalid input format")
+
+// DefaultHeadingPause is the silence inserted before a Markdown heading when
+// Options.HeadingPause is zero.
+const DefaultHeadingPause = time.Second
+
+var (
+	markdownATXHeading  = regexp.MustCompile(`^#{1,6}(?:\s+(.*?))?(?:\s+#+)?\s*$`)
+	markdownSetextLine  = regexp.MustCompile(`^(=+|-+)\s*$`)
+	markdownRule        = regexp.MustCompile(`^(?:(?:\*\s*){3,}|(?:-\s*){3,}|(?:_\s*){3,})$`)
+	markdownFence       = regexp.MustCompile("^(```+|~~~+)")
+	markdownListMarker  = regexp.MustCompile(`^(?:[-*+]|\d+[.)])\s+(?:\[[ xX]\]\s+)?`)
+	markdownReference   = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s*\S`)
+	markdownTableRule   = regexp.MustCompile(`^\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?$`)
+	markdownImage       = regexp.MustCompile(`!\[[^\]]*\](?:\([^)]*\)|\[[^\]]*\])?`)
+	markdownLink        = regexp.MustCompile(`\[([^\]]*)\](?:\([^)]*\)|\[[^\]]*\])`)
+	markdownAutolink    = regexp.MustCompile(`<(?:https?|mailto):[^>]*>`)
+	markdownHTMLComment = regexp.MustCompile(`<!--.*?-->`)
+	markdownHTMLTag     = regexp.MustCompile(`</?[A-Za-z][^>]*>`)
+	markdownCode        = regexp.MustCompile("`+([^`]*)`+")
+	markdownSpacedPunct = regexp.MustCompile(`\s+([.,!?;:。、！？])`)
+	markdownEmphasis    = []*regexp.Regexp{
+		regexp.MustCompile(`\*\*(.+?)\*\*`),
+		regexp.MustCompile(`__(.+?)__`),
+		regexp.MustCompile(`~~(.+?)~~`),
+		regexp.MustCompile(`\*(\S(?:.*?\S)?)\*`),
+		regexp.MustCompile(`(?:^|\b)_(\S(?:.*?\S)?)_(?:\b|$)`),
+	}
+)
+
+// MarkdownSection is the speakable text of a Markdown heading and the prose
+// under it. The text before the first heading forms a section without a
+// heading.
+type MarkdownSection struct {
+	Heading string
+	// Text is the heading followed by its paragraphs, one per line.
+	Text string
+}
+
+// ParseMarkdown converts Markdown into speakable sections. Markup is
+// stripped, fenced code blocks, images, HTML comments and link reference
+// definitions are dropped, and links are read by their text. Sections without
+// any text are omitted.
+func ParseMarkdown(src string) []MarkdownSection {
+	var sections []MarkdownSection
+	current := MarkdownSection{}
+	var paragraphs []string
+	var paragraph string
+	fence := ""
+
+	flushParagraph := func() {
+		if paragraph != "" {
+			paragraphs = append(paragraphs, paragraph)
+			paragraph = ""
+		}
+	}
+	flushSection := func() {
+		flushParagraph()
+		if len(paragraphs) > 0 {
+			current.Text = strings.Join(paragraphs, "\n")
+			sections = append(sections, current)
+		}
+		current = MarkdownSection{}
+		paragraphs = nil
+	}
+	startSection := func(heading string) {
+		flushSection()
+		current.Heading = heading
+		if heading != "" {
+			paragraphs = append(paragraphs, heading)
+		}
+	}
+
+	src = markdownHTMLComment.ReplaceAllString(strings.ReplaceAll(src, "\r\n", "\n"), "")
+	for _, line := range strings.Split(src, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if match := markdownFence.FindString(trimmed); match != "" {
+			flushParagraph()
+			fence = match
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			flushParagraph()
+		case markdownSetextLine.MatchString(trimmed) && paragraph != "":
+			heading := paragraph
+			paragraph = ""
+			startSection(heading)
+		case markdownRule.MatchString(trimmed):
+			flushParagraph()
+		case markdownATXHeading.MatchString(trimmed):
+			startSection(markdownInline(markdownATXHeading.FindStringSubmatch(trimmed)[1]))
+		case markdownReference.MatchString(line), markdownTableRule.MatchString(trimmed) && strings.Contains(trimmed, "|"):
+		default:
+			text := markdownInline(markdownBlockText(trimmed))
+			if text == "" {
+				continue
+			}
+			if strings.HasPrefix(trimmed, "|") || markdownListMarker.MatchString(trimmed) {
+				// Table rows and list items are read one at a time.
+				flushParagraph()
+			}
+			paragraph = joinChunk(paragraph, text)
+		}
+	}
+	flushSection()
+
+	return sections
+}
+
+// MarkdownText converts Markdown into plain speakable text, one paragraph
+// per line. See ParseMarkdown.
+func MarkdownText(src string) string {
+	var texts []string
+	for _, section := range ParseMarkdown(src) {
+		texts = append(texts, section.Text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// markdownBlockText strips block quote, list and table markers from a line.
+func markdownBlockText(line string) string {
+	for strings.HasPrefix(line, ">") {
+		line = strings.TrimSpace(strings.TrimPrefix(line, ">"))
+	}
+	line = markdownListMarker.ReplaceAllString(line, "")
+	if strings.HasPrefix(line, "|") {
+		var cells []string
+		for _, cell := range strings.Split(strings.Trim(line, "|"), "|") {
+			if cell = strings.TrimSpace(cell); cell != "" {
+				cells = append(cells, cell)
+			}
+		}
+		line = strings.Join(cells, "、")
+	}
+	return line
+}
+
+// markdownInline strips inline markup, keeping the text that is read aloud.
+func markdownInline(text string) string {
+	text = markdownImage.ReplaceAllString(text, "")
+	text = markdownLink.ReplaceAllString(text, "$1")
+	text = markdownAutolink.ReplaceAllString(text, "")
+	text = markdownHTMLTag.ReplaceAllString(text, "")
+	text = markdownCode.ReplaceAllString(text, "$1")
+	for _, emphasis := range markdownEmphasis {
+		text = emphasis.ReplaceAllString(text, "$1")
+	}
+	text = strings.NewReplacer(`\*`, "*", `\_`, "_", `\#`, "#", `\[`, "[", `\]`, "]", "\\`", "`").Replace(text)
+	return markdownSpacedPunct.ReplaceAllString(strings.Join(strings.Fields(text), " "), "$1")
+}
+
+// generateMarkdownSpeech renders the Markdown document src into opts.Output,
+// separating sections with opts.HeadingPause, or into one file per section
+// with opts.SplitSections.
+func (c *Client) generateMarkdownSpeech(ctx context.Context, src string, opts Options) error {
+	sections := ParseMarkdown(src)
+	if len(sections) == 0 {
+		return fmt.Errorf("markdown contains no text to speak")
+	}
+
+	if opts.SplitSections {
+		name := opts.Output
+		if name == "" {
+			name = WavName
+		}
+		for i, section := range sections {
+			sectionOpts := opts
+			sectionOpts.Output = sectionOutput(name, i+1)
+			if err := c.generateSpeech(ctx, section.Text, plainText(sectionOpts)); err != nil {
+				return fmt.Errorf("section %d: %w", i+1, err)
+			}
+		}
+		return nil
+	}
+
+	formats, err := parseSubtitleFormats(opts.Subtitles)
+	if err != nil {
+		return err
+	}
+
+	tempDir, err := os.MkdirTemp("", "vpeak-markdown-")
+	if err != nil {
+		return fmt.Errorf("create section directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	pause := opts.HeadingPause
+	if pause == 0 {
+		pause = DefaultHeadingPause
+	}
+
+	var clips []*wav.Audio
+	var cues []Cue
+	var offset time.Duration
+	for i, section := range sections {
+		sectionOpts := opts
+		sectionOpts.Output = filepath.Join(tempDir, fmt.Sprintf("section-%03d.wav", i))
+		sectionCues, err := c.render(ctx, section.Text, sectionOpts, len(formats) > 0)
+		if err != nil {
+			return fmt.Errorf("section %d: %w", i+1, err)
+		}
+
+		clip, err := wav.ReadFile(sectionOpts.Output)
+		if err != nil {
+			return fmt.Errorf("section %d: %w", i+1, err)
+		}
+
+		if i > 0 {
+			silence := wav.Silence(clip.Format, pause)
+			clips = append(clips, silence)
+			offset += silence.Duration()
+		}
+		for _, cue := range sectionCues {
+			cues = append(cues, Cue{Start: offset + cue.Start, End: offset + cue.End, Text: cue.Text})
+		}
+		clips = append(clips, clip)
+		offset += clip.Duration()
+	}
+
+	joined, err := wav.Concat(clips, 0)
+	if err != nil {
+		return fmt.Errorf("join sections: %w", err)
+	}
+	output := c.outputPath(opts)
+	if err := joined.WriteFile(output); err != nil {
+		return err
+	}
+	if err := writeSubtitles(output, formats, cues); err != nil {
+		return err
+	}
+
+	return c.playOutput(ctx, opts)
+}
+
+// sectionOutput returns the output of the n-th section of a Markdown
+// document rendered with Options.SplitSections, e.g. chapter-01.wav.
+func sectionOutput(output string, n int) string {
+	ext := filepath.Ext(output)
+	return fmt.Sprintf("%s-%02d%s", strings.TrimSuffix(output, ext), n, ext)
+}
+
+// plainText returns opts for rendering text that is already plain.
+func plainText(opts Options) Options {
+	opts.Format = ""
+	return opts
+}
@@ -0,0 +1,185 @@
+package vpeak
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shinshin86/vpeak/wav"
+)
+
+func TestParseMarkdown(t *testing.T) {
+	src := "Intro with **bold** and `code`.\n" +
+		"<!-- a comment -->\n" +
+		"\n" +
+		"# Chapter *One* #\n" +
+		"\n" +
+		"See [the docs](https://example.com) and <https://example.com>.\n" +
+		"![diagram](diagram.png)\n" +
+		"\n" +
+		"```go\n" +
+		"fmt.Println(\"skipped\")\n" +
+		"```\n" +
+		"\n" +
+		"> quoted\n" +
+		"> text\n" +
+		"\n" +
+		"- first item\n" +
+		"- [x] second_item\n" +
+		"\n" +
+		"[docs]: https://example.com\n" +
+		"\n" +
+		"Chapter Two\n" +
+		"-----------\n" +
+		"\n" +
+		"| a | b |\n" +
+		"|---|---|\n" +
+		"| 1 | 2 |\n" +
+		"\n" +
+		"***\n" +
+		"日本語の\n" +
+		"文章です。\n" +
+		"\n" +
+		"## Empty section\n" +
+		"```\n" +
+		"only code\n" +
+		"```\n"
+
+	want := []MarkdownSection{
+		{Text: "Intro with bold and code."},
+		{
+			Heading: "Chapter One",
+			Text:    "Chapter One\nSee the docs and.\nquoted text\nfirst item\nsecond_item",
+		},
+		{
+			Heading: "Chapter Two",
+			Text:    "Chapter Two\na、b\n1、2\n日本語の文章です。",
+		},
+		{Heading: "Empty section", Text: "Empty section"},
+	}
+
+	got := ParseMarkdown(src)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseMarkdown() =\n%#v\nwant\n%#v", got, want)
+	}
+}
+
+func TestMarkdownText(t *testing.T) {
+	got := MarkdownText("# Title\n\nHello *world*.\n\n```\ncode\n```\n")
+	if want := "Title\nHello world."; got != want {
+		t.Fatalf("MarkdownText() = %q, want %q", got, want)
+	}
+}
+
+func TestClientGenerateSpeechMarkdown(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "doc.wav")
+	engine := &fakeEngine{audio: testWAV(800)}
+	client := &Client{Engine: engine}
+
+	src := "# One\n\nFirst.\n\n# Two\n\nSecond."
+	err := client.GenerateSpeech(src, Options{
+		Output:       output,
+		Silent:       true,
+		Format:       FormatMarkdown,
+		HeadingPause: 500 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("GenerateSpeech() error = %v", err)
+	}
+	if len(engine.calls) != 2 {
+		t.Fatalf("engine calls = %d, want one per section", len(engine.calls))
+	}
+	if got := engine.calls[0][len(engine.calls[0])-1]; got != "One\nFirst." {
+		t.Fatalf("first section text = %q", got)
+	}
+
+	clip, err := wav.ReadFile(output)
+	if err != nil {
+		t.Fatalf("wav.ReadFile() error = %v", err)
+	}
+	if want := 700 * time.Millisecond; clip.Duration() != want {
+		t.Fatalf("Duration() = %v, want %v", clip.Duration(), want)
+	}
+}
+
+func TestClientGenerateSpeechMarkdownSplitSections(t *testing.T) {
+	dir := t.TempDir()
+	client := &Client{Engine: &fakeEngine{audio: testWAV(10)}}
+
+	src := "Preface.\n\n# One\n\nFirst.\n\n# Two\n\nSecond."
+	err := client.GenerateSpeech(src, Options{
+		Output:        filepath.Join(dir, "doc.wav"),
+		Silent:        true,
+		Format:        FormatMarkdown,
+		SplitSections: true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateSpeech() error = %v", err)
+	}
+
+	for _, name := range []string{"doc-01.wav", "doc-02.wav", "doc-03.wav"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("section file %s: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "doc.wav")); err == nil {
+		t.Fatal("doc.wav written, want only section files")
+	}
+}
+
+func TestClientGenerateSpeechMarkdownNegativeHeadingPause(t *testing.T) {
+	engine := &fakeEngine{audio: testWAV(10)}
+	client := &Client{Engine: engine}
+
+	src := "# One\n\nFirst.\n\n# Two\n\nSecond."
+	output := filepath.Join(t.TempDir(), "doc.wav")
+	err := client.GenerateSpeech(src, Options{Output: output, Silent: true, Format: FormatMarkdown, HeadingPause: -time.Second})
+	if err == nil {
+		t.Fatal("GenerateSpeech() error = nil, want error")
+	}
+	if len(engine.calls) != 0 {
+		t.Fatalf("engine calls = %d, want none", len(engine.calls))
+	}
+}
+
+func TestClientGenerateSpeechInvalidFormat(t *testing.T) {
+	client := &Client{Engine: &fakeEngine{}}
+	err := client.GenerateSpeech("hi", Options{Silent: true, Format: "rst"})
+	if !errors.Is(err, ErrInvalidFormat) {
+		t.Fatalf("GenerateSpeech() error = %v, want ErrInvalidFormat", err)
+	}
+}
+
+func TestClientProcessTextFilesMarkdown(t *testing.T) {
+	dir := t.TempDir()
+	writeTextFiles(t, dir, map[string]string{
+		"a.md":       "# A\n\ntext",
+		"b.markdown": "# B\n\ntext",
+		"c.txt":      "plain",
+	})
+
+	client := &Client{Engine: &fakeEngine{audio: testWAV(10)}}
+	opts := Options{Silent: true, Format: FormatMarkdown, SplitSections: true, Resume: true}
+	result, err := client.ProcessTextFiles(dir, opts)
+	if err != nil {
+		t.Fatalf("ProcessTextFiles() error = %v", err)
+	}
+	if len(result.Files) != 2 || len(result.Failed()) != 0 {
+		t.Fatalf("Files = %#v, want a.md and b.markdown rendered", result.Files)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a-01.wav")); err != nil {
+		t.Fatalf("section file: %v", err)
+	}
+
+	result, err = client.ProcessTextFiles(dir, opts)
+	if err != nil {
+		t.Fatalf("ProcessTextFiles() error = %v", err)
+	}
+	if result.Skipped() != 2 {
+		t.Fatalf("Skipped() = %d, want 2", result.Skipped())
+	}
+}
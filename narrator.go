@@ -0,0 +1,215 @@
+package vpeak
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+var (
+	ErrUnknownNarrator      = errors.New("unknown narrator")
+	ErrInvalidNarratorAlias = errors.New("invalid narrator alias")
+)
+
+// NarratorAlias is a short name accepted in place of a narrator's full name.
+type NarratorAlias struct {
+	Alias    string
+	Narrator string
+}
+
+var (
+	narratorAliasMu sync.RWMutex
+	// narratorAliases holds the aliases in the order they were added.
+	narratorAliases = []NarratorAlias{
+		{"f1", "Japanese Female 1"},
+		{"f2", "Japanese Female 2"},
+		{"f3", "Japanese Female 3"},
+		{"m1", "Japanese Male 1"},
+		{"m2", "Japanese Male 2"},
+		{"m3", "Japanese Male 3"},
+		{"c", "Japanese Female Child"},
+	}
+)
+
+// AddNarratorAlias makes alias resolve to the narrator named narrator,
+// replacing any previous alias of the same name.
+func AddNarratorAlias(alias, narrator string) error {
+	alias = strings.TrimSpace(alias)
+	narrator = strings.TrimSpace(narrator)
+	if alias == "" || narrator == "" {
+		return fmt.Errorf("%w: alias and narrator are required", ErrInvalidNarratorAlias)
+	}
+	if strings.IndexFunc(alias, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("%w: %q contains spaces", ErrInvalidNarratorAlias, alias)
+	}
+
+	narratorAliasMu.Lock()
+	defer narratorAliasMu.Unlock()
+
+	for i, a := range narratorAliases {
+		if a.Alias == alias {
+			narratorAliases[i].Narrator = narrator
+			return nil
+		}
+	}
+	narratorAliases = append(narratorAliases, NarratorAlias{Alias: alias, Narrator: narrator})
+	return nil
+}
+
+// NarratorAliases returns the registered aliases in the order they were
+// added, starting with the built-in f1–f3, m1–m3 and c.
+func NarratorAliases() []NarratorAlias {
+	narratorAliasMu.RLock()
+	defer narratorAliasMu.RUnlock()
+	return append([]NarratorAlias(nil), narratorAliases...)
+}
+
+func resolveNarratorName(narrator string) string {
+	narratorAliasMu.RLock()
+	defer narratorAliasMu.RUnlock()
+
+	for _, a := range narratorAliases {
+		if a.Alias == narrator {
+			return a.Narrator
+		}
+	}
+	return narrator
+}
+
+// ResolveNarrator resolves name with DefaultClient. See Client.ResolveNarrator.
+func ResolveNarrator(ctx context.Context, name string) (string, error) {
+	return DefaultClient.ResolveNarrator(ctx, name)
+}
+
+// ResolveNarrator returns the installed narrator meant by name: an alias, an
+// exact or case-insensitive match, or the only narrator whose name starts
+// with name. Otherwise it returns ErrUnknownNarrator, suggesting the closest
+// installed names. If VOICEPEAK lists no narrators, name is returned as it
+// is after alias resolution. A name missing from a cached listing is looked
+// up again, since installing a narrator does not change the executable.
+func (c *Client) ResolveNarrator(ctx context.Context, name string) (string, error) {
+	name = resolveNarratorName(strings.TrimSpace(name))
+	if name == "" {
+		return "", fmt.Errorf("%w: narrator is required", ErrUnknownNarrator)
+	}
+
+	narrators, err := c.ListNarratorsContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(narrators) == 0 {
+		return name, nil
+	}
+
+	narrator, err := matchNarrator(name, narrators)
+	if err == nil || !c.usesCatalog() {
+		return narrator, err
+	}
+	fresh, listErr := c.list(ctx, true, "--list-narrator")
+	if listErr != nil || len(fresh) == 0 {
+		return "", err
+	}
+	return matchNarrator(name, fresh)
+}
+
+// matchNarrator finds name among the installed narrators.
+func matchNarrator(name string, narrators []string) (string, error) {
+	for _, narrator := range narrators {
+		if narrator == name {
+			return narrator, nil
+		}
+	}
+
+	lower := strings.ToLower(name)
+	var prefixed []string
+	for _, narrator := range narrators {
+		if strings.ToLower(narrator) == lower {
+			return narrator, nil
+		}
+		if strings.HasPrefix(strings.ToLower(narrator), lower) {
+			prefixed = append(prefixed, narrator)
+		}
+	}
+	if len(prefixed) == 1 {
+		return prefixed[0], nil
+	}
+	if len(prefixed) > 1 {
+		return "", fmt.Errorf("%w: %q is ambiguous (matches %s)", ErrUnknownNarrator, name, quoteList(prefixed))
+	}
+
+	if suggestions := suggestNarrators(name, narrators); len(suggestions) > 0 {
+		return "", fmt.Errorf("%w: %q (did you mean %s?)", ErrUnknownNarrator, name, quoteList(suggestions))
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownNarrator, name)
+}
+
+// suggestNarrators returns up to three narrators whose names are close to
+// name, closest first.
+func suggestNarrators(name string, narrators []string) []string {
+	type candidate struct {
+		narrator string
+		distance int
+	}
+
+	lower := []rune(strings.ToLower(name))
+	var candidates []candidate
+	for _, narrator := range narrators {
+		distance := editDistance(lower, []rune(strings.ToLower(narrator)))
+		limit := len(lower) / 3
+		if limit < 2 {
+			limit = 2
+		}
+		if distance <= limit || strings.Contains(strings.ToLower(narrator), string(lower)) {
+			candidates = append(candidates, candidate{narrator, distance})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	var suggestions []string
+	for i := 0; i < len(candidates) && i < 3; i++ {
+		suggestions = append(suggestions, candidates[i].narrator)
+	}
+	return suggestions
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func quoteList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = fmt.Sprintf("%q", item)
+	}
+	return strings.Join(quoted, ", ")
+}
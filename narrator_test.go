@@ -0,0 +1,125 @@
+package vpeak
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// restoreNarratorAliases undoes the aliases added by a test.
+func restoreNarratorAliases(t *testing.T) {
+	t.Helper()
+	saved := NarratorAliases()
+	t.Cleanup(func() {
+		narratorAliasMu.Lock()
+		narratorAliases = saved
+		narratorAliasMu.Unlock()
+	})
+}
+
+func TestAddNarratorAlias(t *testing.T) {
+	restoreNarratorAliases(t)
+
+	if err := AddNarratorAlias("moka", "宮舞モカ"); err != nil {
+		t.Fatalf("AddNarratorAlias() error = %v", err)
+	}
+	if err := AddNarratorAlias("f1", "夏色花梨"); err != nil {
+		t.Fatalf("AddNarratorAlias() error = %v", err)
+	}
+
+	if got := resolveNarratorName("moka"); got != "宮舞モカ" {
+		t.Errorf("resolveNarratorName(moka) = %q, want %q", got, "宮舞モカ")
+	}
+	if got := resolveNarratorName("f1"); got != "夏色花梨" {
+		t.Errorf("resolveNarratorName(f1) = %q, want %q", got, "夏色花梨")
+	}
+
+	aliases := NarratorAliases()
+	if aliases[0] != (NarratorAlias{"f1", "夏色花梨"}) || aliases[len(aliases)-1] != (NarratorAlias{"moka", "宮舞モカ"}) {
+		t.Errorf("NarratorAliases() = %v, want f1 replaced in place and moka last", aliases)
+	}
+
+	for _, alias := range []string{"", "two words"} {
+		if err := AddNarratorAlias(alias, "宮舞モカ"); !errors.Is(err, ErrInvalidNarratorAlias) {
+			t.Errorf("AddNarratorAlias(%q) error = %v, want %v", alias, err, ErrInvalidNarratorAlias)
+		}
+	}
+}
+
+func TestMatchNarrator(t *testing.T) {
+	narrators := []string{"Japanese Female 1", "Japanese Female 2", "Japanese Male 1", "宮舞モカ", "夏色花梨"}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{name: "exact", input: "宮舞モカ", want: "宮舞モカ"},
+		{name: "case-insensitive", input: "japanese male 1", want: "Japanese Male 1"},
+		{name: "unique prefix", input: "夏色", want: "夏色花梨"},
+		{name: "ambiguous prefix", input: "Japanese Female", wantErr: `is ambiguous (matches "Japanese Female 1", "Japanese Female 2")`},
+		{name: "typo", input: "Japanese Mal 1", wantErr: `did you mean "Japanese Male 1"`},
+		{name: "no match", input: "Zundamon", wantErr: `unknown narrator: "Zundamon"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchNarrator(tt.input, narrators)
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrUnknownNarrator) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("matchNarrator(%q) error = %v, want %q", tt.input, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("matchNarrator(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientResolveNarrator(t *testing.T) {
+	restoreNarratorAliases(t)
+	if err := AddNarratorAlias("moka", "宮舞モカ"); err != nil {
+		t.Fatalf("AddNarratorAlias() error = %v", err)
+	}
+
+	engine := &fakeEngine{output: "[debug] noise\n宮舞モカ\n夏色花梨\n"}
+	client := &Client{Engine: engine}
+
+	got, err := client.ResolveNarrator(context.Background(), "moka")
+	if err != nil || got != "宮舞モカ" {
+		t.Fatalf("ResolveNarrator(moka) = %q, %v, want %q", got, err, "宮舞モカ")
+	}
+
+	want := [][]string{{"--list-narrator"}}
+	if !reflect.DeepEqual(engine.calls, want) {
+		t.Fatalf("engine calls = %#v, want %#v", engine.calls, want)
+	}
+
+	if _, err := client.ResolveNarrator(context.Background(), "f1"); !errors.Is(err, ErrUnknownNarrator) {
+		t.Fatalf("ResolveNarrator(f1) error = %v, want %v", err, ErrUnknownNarrator)
+	}
+}
+
+func TestConfigAddNarratorAliases(t *testing.T) {
+	restoreNarratorAliases(t)
+	useConfig(t, `{"narrator_aliases": {"moka": "宮舞モカ", "karin": "夏色花梨"}}`, `{"narrator_aliases": {"karin": "小春六花"}}`)
+
+	config, err := LoadDefaultConfig()
+	if err != nil {
+		t.Fatalf("LoadDefaultConfig() error = %v", err)
+	}
+	if err := config.AddNarratorAliases(); err != nil {
+		t.Fatalf("AddNarratorAliases() error = %v", err)
+	}
+
+	for alias, want := range map[string]string{"moka": "宮舞モカ", "karin": "小春六花"} {
+		if got := resolveNarratorName(alias); got != want {
+			t.Errorf("resolveNarratorName(%q) = %q, want %q", alias, got, want)
+		}
+	}
+}
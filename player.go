@@ -0,0 +1,104 @@
+package vpeak
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+var ErrPlayerNotFound = errors.New("audio player not found")
+
+// PlayerEnv is the environment variable that overrides the audio player, e.g.
+// VPEAK_PLAYER=mpv or VPEAK_PLAYER="ffplay -nodisp -autoexit".
+const PlayerEnv = "VPEAK_PLAYER"
+
+// Player plays audio files.
+type Player interface {
+	// Play plays the file at path and returns once playback has finished.
+	// Implementations should stop and return ctx.Err() once ctx is done.
+	Play(ctx context.Context, path string) error
+}
+
+// CommandPlayer plays files by running Command with Args followed by the
+// file's path.
+type CommandPlayer struct {
+	Command string
+	Args    []string
+}
+
+// Play runs the player command on path.
+func (p *CommandPlayer) Play(ctx context.Context, path string) error {
+	cmd := exec.CommandContext(ctx, p.Command, append(append([]string(nil), p.Args...), path)...)
+	killProcessTreeOnCancel(cmd)
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("wav file play failed: %v", err)
+	}
+	return nil
+}
+
+// knownPlayers holds the arguments that make each supported player play a
+// file once without a window or console output.
+var knownPlayers = map[string][]string{
+	"afplay":  nil,
+	"aplay":   {"-q"},
+	"paplay":  nil,
+	"pw-play": nil,
+	"ffplay":  {"-nodisp", "-autoexit", "-loglevel", "quiet"},
+	"mpv":     {"--no-video", "--really-quiet"},
+}
+
+// linuxPlayers is the order in which players are looked for on Linux and
+// other Unix systems.
+var linuxPlayers = []string{"pw-play", "paplay", "aplay", "ffplay", "mpv"}
+
+// NewPlayer returns the player named by spec: one of afplay, aplay, paplay,
+// pw-play, ffplay and mpv, or any command line that accepts the file path as
+// its last argument. The command must be found in PATH.
+func NewPlayer(spec string) (Player, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: empty player", ErrPlayerNotFound)
+	}
+
+	path, err := exec.LookPath(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPlayerNotFound, err)
+	}
+
+	args := fields[1:]
+	if len(args) == 0 {
+		args = knownPlayers[fields[0]]
+	}
+	return &CommandPlayer{Command: path, Args: args}, nil
+}
+
+// DefaultPlayer returns the player named by $VPEAK_PLAYER if set, afplay on
+// macOS, the file's associated application on Windows, and otherwise the
+// first of pw-play, paplay, aplay, ffplay and mpv found in PATH.
+func DefaultPlayer() (Player, error) {
+	if spec := os.Getenv(PlayerEnv); spec != "" {
+		return NewPlayer(spec)
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return &CommandPlayer{Command: "afplay"}, nil
+	case "windows":
+		return &CommandPlayer{Command: "cmd", Args: []string{"/c", "start", ""}}, nil
+	}
+
+	for _, name := range linuxPlayers {
+		if player, err := NewPlayer(name); err == nil {
+			return player, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: install one of %s or set %s", ErrPlayerNotFound, strings.Join(linuxPlayers, ", "), PlayerEnv)
+}
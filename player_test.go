@@ -0,0 +1,137 @@
+package vpeak
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+type fakePlayer struct {
+	played []string
+}
+
+func (p *fakePlayer) Play(ctx context.Context, path string) error {
+	p.played = append(p.played, path)
+	return nil
+}
+
+// installFakePlayer puts a shell script named name on PATH that writes its
+// arguments to a file, and returns that file's path.
+func installFakePlayer(t *testing.T, name string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	log := filepath.Join(dir, "args")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\" > " + log + "\n"
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	t.Setenv("PATH", dir)
+	return log
+}
+
+func readArgs(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	return strings.Fields(string(data))
+}
+
+func TestNewPlayer(t *testing.T) {
+	log := installFakePlayer(t, "mpv")
+
+	tests := []struct {
+		spec string
+		want []string
+	}{
+		{spec: "mpv", want: []string{"--no-video", "--really-quiet", "a.wav"}},
+		{spec: "mpv --volume=50", want: []string{"--volume=50", "a.wav"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			player, err := NewPlayer(tt.spec)
+			if err != nil {
+				t.Fatalf("NewPlayer() error = %v", err)
+			}
+			if err := player.Play(context.Background(), "a.wav"); err != nil {
+				t.Fatalf("Play() error = %v", err)
+			}
+			if got := readArgs(t, log); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("player args = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewPlayer("aplay"); !errors.Is(err, ErrPlayerNotFound) {
+		t.Fatalf("NewPlayer(aplay) error = %v, want ErrPlayerNotFound", err)
+	}
+}
+
+func TestDefaultPlayer(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("macOS always uses afplay")
+	}
+	log := installFakePlayer(t, "ffplay")
+
+	t.Run("detect", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("Windows uses the associated application")
+		}
+		t.Setenv(PlayerEnv, "")
+		player, err := DefaultPlayer()
+		if err != nil {
+			t.Fatalf("DefaultPlayer() error = %v", err)
+		}
+		if err := player.Play(context.Background(), "a.wav"); err != nil {
+			t.Fatalf("Play() error = %v", err)
+		}
+		if got := readArgs(t, log); got[0] != "-nodisp" {
+			t.Fatalf("player args = %q, want ffplay arguments", got)
+		}
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv(PlayerEnv, "ffplay -v")
+		player, err := DefaultPlayer()
+		if err != nil {
+			t.Fatalf("DefaultPlayer() error = %v", err)
+		}
+		if err := player.Play(context.Background(), "a.wav"); err != nil {
+			t.Fatalf("Play() error = %v", err)
+		}
+		if got, want := readArgs(t, log), []string{"-v", "a.wav"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("player args = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("env not found", func(t *testing.T) {
+		t.Setenv(PlayerEnv, "missing-player")
+		if _, err := DefaultPlayer(); !errors.Is(err, ErrPlayerNotFound) {
+			t.Fatalf("DefaultPlayer() error = %v, want ErrPlayerNotFound", err)
+		}
+	})
+}
+
+func TestClientGenerateSpeechUsesPlayer(t *testing.T) {
+	player := &fakePlayer{}
+	client := &Client{Engine: &fakeEngine{}, Player: player}
+
+	output := filepath.Join(t.TempDir(), "hi.wav")
+	if err := client.GenerateSpeech("hi", Options{Output: output}); err != nil {
+		t.Fatalf("GenerateSpeech() error = %v", err)
+	}
+	if want := []string{output}; !reflect.DeepEqual(player.played, want) {
+		t.Fatalf("played = %q, want %q", player.played, want)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !unix
+
+package vpeak
+
+import (
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// killProcessTreeOnCancel kills cmd and, on Windows, its child processes when
+// the command's context is done.
+func killProcessTreeOnCancel(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		if runtime.GOOS == "windows" {
+			kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+			if err := kill.Run(); err == nil {
+				return nil
+			}
+		}
+		return cmd.Process.Kill()
+	}
+	cmd.WaitDelay = 5 * time.Second
+}
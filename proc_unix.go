@@ -0,0 +1,23 @@
+//go:build unix
+
+package vpeak
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// killProcessTreeOnCancel starts cmd in its own process group and kills the
+// whole group when the command's context is done, so helper processes spawned
+// by VOICEPEAK or the audio player do not outlive it.
+func killProcessTreeOnCancel(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = 5 * time.Second
+}
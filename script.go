@@ -0,0 +1,235 @@
+package vpeak
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shinshin86/vpeak/wav"
+)
+
+var ErrInvalidScript = errors.New("invalid script")
+
+var scriptLinePattern = regexp.MustCompile(`^([^:：\[\]]+?)\s*(?:\[([^\]]*)\])?\s*[:：]\s*(.*)$`)
+
+// Script is a multi-speaker dialogue.
+type Script struct {
+	Lines []ScriptLine
+}
+
+// ScriptLine is one spoken line of a Script. Emotion, Speed, Pitch and Pause
+// are empty or nil unless set by the line's modifiers.
+type ScriptLine struct {
+	// Number is the 1-based line number in the source.
+	Number   int
+	Narrator string
+	Text     string
+	Emotion  string
+	Speed    *int
+	Pitch    *int
+	// Pause overrides the silence inserted after this line.
+	Pause *time.Duration
+}
+
+// ParseScript reads a dialogue script. Each non-blank line has the form
+//
+//	narrator[modifiers]: text
+//
+// where narrator is an alias such as f1 or an installed narrator name, and the
+// optional comma-separated modifiers are speed=N, pitch=N, pause=DURATION or
+// emotion weights (happy=50). Full-width colons are accepted and lines
+// starting with # are comments.
+func ParseScript(r io.Reader) (*Script, error) {
+	script := &Script{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	number := 0
+	for scanner.Scan() {
+		number++
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" || strings.HasPrefix(raw, "#") {
+			continue
+		}
+
+		line, err := parseScriptLine(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidScript, number, err)
+		}
+		line.Number = number
+		script.Lines = append(script.Lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return script, nil
+}
+
+// ParseScriptFile reads the dialogue script at path.
+func ParseScriptFile(path string) (*Script, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseScript(file)
+}
+
+func parseScriptLine(raw string) (ScriptLine, error) {
+	match := scriptLinePattern.FindStringSubmatch(raw)
+	if match == nil {
+		return ScriptLine{}, fmt.Errorf("expected \"narrator: text\"")
+	}
+
+	line := ScriptLine{Narrator: strings.TrimSpace(match[1]), Text: strings.TrimSpace(match[3])}
+	if line.Text == "" {
+		return ScriptLine{}, fmt.Errorf("text is empty")
+	}
+
+	var emotions []string
+	for _, modifier := range strings.Split(match[2], ",") {
+		modifier = strings.TrimSpace(modifier)
+		if modifier == "" {
+			continue
+		}
+
+		name, value, _ := strings.Cut(modifier, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		switch name {
+		case "speed":
+			speed, err := parseScriptInt(name, value, 50, 200)
+			if err != nil {
+				return ScriptLine{}, err
+			}
+			line.Speed = &speed
+		case "pitch":
+			pitch, err := parseScriptInt(name, value, -300, 300)
+			if err != nil {
+				return ScriptLine{}, err
+			}
+			line.Pitch = &pitch
+		case "pause":
+			pause, err := time.ParseDuration(value)
+			if err != nil || pause < 0 {
+				return ScriptLine{}, fmt.Errorf("invalid pause: %q", value)
+			}
+			line.Pause = &pause
+		default:
+			emotions = append(emotions, modifier)
+		}
+	}
+
+	if len(emotions) > 0 {
+		line.Emotion = strings.Join(emotions, ",")
+		if _, err := normalizeEmotionExpression(line.Emotion); err != nil {
+			return ScriptLine{}, fmt.Errorf("%w %q: %v", ErrInvalidEmotion, line.Emotion, err)
+		}
+	}
+
+	return line, nil
+}
+
+func parseScriptInt(name, value string, min, max int) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %q", name, value)
+	}
+	if n < min || n > max {
+		return 0, fmt.Errorf("%s must be between %d and %d", name, min, max)
+	}
+	return n, nil
+}
+
+// RenderScript renders script with DefaultClient. See Client.RenderScript.
+func RenderScript(ctx context.Context, script *Script, opts Options, pause time.Duration) error {
+	return DefaultClient.RenderScript(ctx, script, opts, pause)
+}
+
+// RenderScript renders script into a single audio file at opts.Output. Each
+// line is synthesized with opts overridden by the line's narrator and
+// modifiers, and pause of silence is inserted between lines unless a line
+// sets its own pause. pause must not be negative. The result is played
+// unless opts.Silent is set.
+func (c *Client) RenderScript(ctx context.Context, script *Script, opts Options, pause time.Duration) error {
+	if len(script.Lines) == 0 {
+		return fmt.Errorf("%w: no lines to render", ErrInvalidScript)
+	}
+	if pause < 0 {
+		return fmt.Errorf("pause must not be negative: %s", pause)
+	}
+
+	opts, err := resolveProfile(opts)
+	if err != nil {
+		return err
+	}
+
+	tempDir, err := os.MkdirTemp("", "vpeak-script-")
+	if err != nil {
+		return fmt.Errorf("create script directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	var clips []*wav.Audio
+	for i, line := range script.Lines {
+		lineOpts, err := c.validate(ctx, line.options(opts))
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line.Number, err)
+		}
+		lineOpts.Output = filepath.Join(tempDir, fmt.Sprintf("line-%03d.wav", i))
+		if err := c.synthesize(ctx, line.Text, lineOpts); err != nil {
+			return fmt.Errorf("line %d: %w", line.Number, err)
+		}
+
+		clip, err := wav.ReadFile(lineOpts.Output)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line.Number, err)
+		}
+
+		if i > 0 {
+			gap := pause
+			if previous := script.Lines[i-1]; previous.Pause != nil {
+				gap = *previous.Pause
+			}
+			clips = append(clips, wav.Silence(clip.Format, gap))
+		}
+		clips = append(clips, clip)
+	}
+
+	joined, err := wav.Concat(clips, 0)
+	if err != nil {
+		return fmt.Errorf("join script lines: %w", err)
+	}
+
+	if err := joined.WriteFile(c.outputPath(opts)); err != nil {
+		return err
+	}
+
+	return c.playOutput(ctx, opts)
+}
+
+// options returns base overridden by the line's narrator and modifiers.
+func (l ScriptLine) options(base Options) Options {
+	opts := base
+	opts.Narrator = l.Narrator
+	opts.Silent = true
+	opts.Subtitles = ""
+	if l.Emotion != "" {
+		opts.Emotion = l.Emotion
+	}
+	if l.Speed != nil {
+		opts.Speed = l.Speed
+	}
+	if l.Pitch != nil {
+		opts.Pitch = l.Pitch
+	}
+	return opts
+}
@@ -0,0 +1,133 @@
+package vpeak
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shinshin86/vpeak/wav"
+)
+
+func TestParseScript(t *testing.T) {
+	src := `# greeting
+f1: こんにちは
+m1 [happy=50, speed=120, pause=1s]：やあ、元気？
+
+Zundamon[pitch=-20]: 時刻は10:30なのだ
+`
+
+	script, err := ParseScript(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("ParseScript() error = %v", err)
+	}
+	if len(script.Lines) != 3 {
+		t.Fatalf("ParseScript() lines = %d, want 3", len(script.Lines))
+	}
+
+	first := script.Lines[0]
+	if first.Number != 2 || first.Narrator != "f1" || first.Text != "こんにちは" || first.Emotion != "" {
+		t.Fatalf("line 1 = %+v", first)
+	}
+
+	second := script.Lines[1]
+	if second.Narrator != "m1" || second.Text != "やあ、元気？" || second.Emotion != "happy=50" {
+		t.Fatalf("line 2 = %+v", second)
+	}
+	if second.Speed == nil || *second.Speed != 120 {
+		t.Fatalf("line 2 speed = %v, want 120", second.Speed)
+	}
+	if second.Pause == nil || *second.Pause != time.Second {
+		t.Fatalf("line 2 pause = %v, want 1s", second.Pause)
+	}
+
+	third := script.Lines[2]
+	if third.Narrator != "Zundamon" || third.Text != "時刻は10:30なのだ" || third.Pitch == nil || *third.Pitch != -20 {
+		t.Fatalf("line 3 = %+v", third)
+	}
+}
+
+func TestParseScriptErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"missing narrator", "こんにちは"},
+		{"empty text", "f1:   "},
+		{"speed out of range", "f1[speed=300]: hi"},
+		{"bad pitch", "f1[pitch=high]: hi"},
+		{"bad pause", "f1[pause=soon]: hi"},
+		{"bad emotion weight", "f1[happy=101]: hi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseScript(strings.NewReader(tt.src)); !errors.Is(err, ErrInvalidScript) {
+				t.Fatalf("ParseScript(%q) error = %v, want ErrInvalidScript", tt.src, err)
+			}
+		})
+	}
+}
+
+func TestClientRenderScript(t *testing.T) {
+	script, err := ParseScript(strings.NewReader("f1: あ\nm1[sad, pause=50ms]: い\nc: う\n"))
+	if err != nil {
+		t.Fatalf("ParseScript() error = %v", err)
+	}
+
+	engine := &fakeEngine{audio: testWAV(100)}
+	client := &Client{Engine: engine}
+	output := filepath.Join(t.TempDir(), "dialogue.wav")
+	speed := 110
+
+	err = client.RenderScript(context.Background(), script, Options{Output: output, Silent: true, Speed: &speed}, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("RenderScript() error = %v", err)
+	}
+
+	if len(engine.calls) != 3 {
+		t.Fatalf("engine calls = %d, want 3", len(engine.calls))
+	}
+	wantArgs := []struct{ narrator, emotion string }{
+		{"Japanese Female 1", ""},
+		{"Japanese Male 1", "sad=100"},
+		{"Japanese Female Child", ""},
+	}
+	for i, want := range wantArgs {
+		args := strings.Join(engine.calls[i], " ")
+		if !strings.Contains(args, "--narrator "+want.narrator) || !strings.Contains(args, "--speed 110") {
+			t.Fatalf("call %d args = %q", i, args)
+		}
+		if want.emotion != "" && !strings.Contains(args, "--emotion "+want.emotion) {
+			t.Fatalf("call %d args = %q, want emotion %s", i, args, want.emotion)
+		}
+	}
+
+	rendered, err := wav.ReadFile(output)
+	if err != nil {
+		t.Fatalf("wav.ReadFile() error = %v", err)
+	}
+	// three 100-frame lines, 100ms (800 frames) after line 1, 50ms (400 frames) after line 2
+	if want := 100 + 800 + 100 + 400 + 100; rendered.Frames() != want {
+		t.Fatalf("Frames() = %d, want %d", rendered.Frames(), want)
+	}
+}
+
+func TestClientRenderScriptNegativePause(t *testing.T) {
+	script, err := ParseScript(strings.NewReader("f1: あ\nm1: い\n"))
+	if err != nil {
+		t.Fatalf("ParseScript() error = %v", err)
+	}
+
+	engine := &fakeEngine{audio: testWAV(100)}
+	client := &Client{Engine: engine}
+	output := filepath.Join(t.TempDir(), "dialogue.wav")
+	if err := client.RenderScript(context.Background(), script, Options{Output: output, Silent: true}, -time.Second); err == nil {
+		t.Fatal("RenderScript() error = nil, want error")
+	}
+	if len(engine.calls) != 0 {
+		t.Fatalf("engine calls = %d, want none", len(engine.calls))
+	}
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/shinshin86/vpeak"
+	"github.com/shinshin86/vpeak/wav"
+)
+
+// openAIVoices maps OpenAI's built-in voice names onto narrator aliases so
+// that clients with a hard-coded voice still work.
+var openAIVoices = map[string]string{
+	"alloy":   "f1",
+	"echo":    "m1",
+	"fable":   "f2",
+	"onyx":    "m2",
+	"nova":    "f3",
+	"shimmer": "c",
+}
+
+// openAIPCMSampleRate is the sample rate of OpenAI's pcm response format.
+const openAIPCMSampleRate = 24000
+
+// OpenAIServer is an http.Handler implementing OpenAI's text-to-speech
+// endpoint, POST /v1/audio/speech, on top of VOICEPEAK. The voice is a
+// narrator name or alias, speed (0.25-4.0) is rescaled onto VOICEPEAK's
+// 50-200 and the model is ignored. Only the wav and pcm response formats are
+// supported; unlike OpenAI, which defaults to mp3, a missing response_format
+// yields wav, so that SDK calls without one work unchanged. pcm is raw
+// 24 kHz 16-bit mono like OpenAI's.
+type OpenAIServer struct {
+	*backend
+	mux *http.ServeMux
+}
+
+// SpeechCreateRequest is the body of POST /v1/audio/speech.
+type SpeechCreateRequest struct {
+	Model          string  `json:"model"`
+	Input          string  `json:"input"`
+	Voice          string  `json:"voice"`
+	Speed          float64 `json:"speed"`
+	ResponseFormat string  `json:"response_format"`
+}
+
+// NewOpenAI returns an OpenAI-compatible server that runs VOICEPEAK through
+// client.
+func NewOpenAI(client *vpeak.Client) *OpenAIServer {
+	s := &OpenAIServer{backend: newBackend(client, ""), mux: http.NewServeMux()}
+	s.mux.HandleFunc("/v1/audio/speech", s.handleSpeech)
+	return s
+}
+
+func (s *OpenAIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *OpenAIServer) handleSpeech(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, writeOpenAIError, []string{http.MethodPost}) {
+		return
+	}
+
+	var req SpeechCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, fmt.Errorf("decode request: %w", err))
+		return
+	}
+	if strings.TrimSpace(req.Input) == "" {
+		writeOpenAIError(w, http.StatusBadRequest, errors.New("input is required"))
+		return
+	}
+	if req.Speed != 0 && (req.Speed < 0.25 || req.Speed > 4) {
+		writeOpenAIError(w, http.StatusBadRequest, errors.New("speed must be between 0.25 and 4.0"))
+		return
+	}
+
+	format := req.ResponseFormat
+	if format == "" {
+		format = "wav"
+	}
+	if format != "wav" && format != "pcm" {
+		writeOpenAIError(w, http.StatusBadRequest, fmt.Errorf("response_format %q is not supported; use wav or pcm", format))
+		return
+	}
+
+	opts := vpeak.Options{Narrator: req.Voice}
+	if alias, ok := openAIVoices[req.Voice]; ok {
+		opts.Narrator = alias
+	}
+	if req.Speed != 0 && req.Speed != 1 {
+		speed := openAISpeed(req.Speed)
+		opts.Speed = &speed
+	}
+
+	audio, err := s.synthesize(r.Context(), req.Input, opts)
+	if err != nil {
+		writeOpenAIError(w, statusFor(err), err)
+		return
+	}
+
+	if format == "pcm" {
+		clip, err := wav.Parse(audio)
+		if err == nil {
+			clip, err = clip.Convert(1, openAIPCMSampleRate)
+		}
+		if err != nil {
+			writeOpenAIError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "audio/pcm")
+		w.Write(clip.Data)
+		return
+	}
+
+	w.Header().Set("Content-Type", "audio/wav")
+	w.Write(audio)
+}
+
+// openAISpeed maps OpenAI's 0.25-4.0 speed onto VOICEPEAK's 50-200, keeping
+// 1.0 at VOICEPEAK's default of 100.
+func openAISpeed(speed float64) int {
+	var scaled float64
+	if speed < 1 {
+		scaled = 50 + (speed-0.25)/0.75*50
+	} else {
+		scaled = 100 + (speed-1)/3*100
+	}
+	return clampInt(int(math.Round(scaled)), 50, 200)
+}
+
+// writeOpenAIError writes err in OpenAI's error shape.
+func writeOpenAIError(w http.ResponseWriter, status int, err error) {
+	errorType := "invalid_request_error"
+	if status >= http.StatusInternalServerError {
+		errorType = "server_error"
+	}
+
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{
+			"message": err.Error(),
+			"type":    errorType,
+			"param":   nil,
+			"code":    nil,
+		},
+	})
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/shinshin86/vpeak"
+)
+
+func TestOpenAISpeech(t *testing.T) {
+	_, engine, _ := newTestServer(t)
+	s := NewOpenAI(&vpeak.Client{Engine: engine})
+
+	rec := serve(s, http.MethodPost, "/v1/audio/speech", `{"model":"tts-1","input":"こんにちは","voice":"m2","speed":2.5,"response_format":"wav"}`)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "audio/wav" {
+		t.Fatalf("POST /v1/audio/speech = %d %s", rec.Code, rec.Body)
+	}
+
+	args := strings.Join(engine.calls[0], " ")
+	for _, want := range []string{"--narrator Japanese Male 2", "--speed 150", "-s こんにちは"} {
+		if !strings.Contains(args, want) {
+			t.Fatalf("args = %q, want %q", args, want)
+		}
+	}
+}
+
+func TestOpenAISpeechDefaultsToWAV(t *testing.T) {
+	_, engine, _ := newTestServer(t)
+	s := NewOpenAI(&vpeak.Client{Engine: engine})
+
+	rec := serve(s, http.MethodPost, "/v1/audio/speech", `{"model":"tts-1","input":"hi","voice":"alloy"}`)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "audio/wav" {
+		t.Fatalf("POST /v1/audio/speech without response_format = %d %s", rec.Code, rec.Body)
+	}
+	if !strings.HasPrefix(rec.Body.String(), "RIFF") {
+		t.Fatal("response is not a WAV file")
+	}
+}
+
+func TestOpenAISpeechPCMAndBuiltinVoice(t *testing.T) {
+	_, engine, _ := newTestServer(t)
+	s := NewOpenAI(&vpeak.Client{Engine: engine})
+
+	rec := serve(s, http.MethodPost, "/v1/audio/speech", `{"model":"tts-1","input":"hi","voice":"shimmer","response_format":"pcm"}`)
+	// 100ms of 8 kHz audio resampled to 24 kHz 16-bit mono
+	if rec.Code != http.StatusOK || rec.Body.Len() != 4800 {
+		t.Fatalf("pcm response = %d with %d bytes, want 4800 raw bytes", rec.Code, rec.Body.Len())
+	}
+	if args := strings.Join(engine.calls[0], " "); !strings.Contains(args, "--narrator Japanese Female Child") {
+		t.Fatalf("args = %q, want shimmer mapped to the child narrator", args)
+	}
+}
+
+func TestOpenAISpeechErrors(t *testing.T) {
+	_, engine, _ := newTestServer(t)
+	s := NewOpenAI(&vpeak.Client{Engine: engine})
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing input", `{"voice":"f1"}`},
+		{"speed out of range", `{"input":"hi","speed":5}`},
+		{"unsupported format", `{"input":"hi","response_format":"mp3"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(s, http.MethodPost, "/v1/audio/speech", tt.body)
+			var body struct {
+				Error struct {
+					Message string `json:"message"`
+					Type    string `json:"type"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusBadRequest || body.Error.Type != "invalid_request_error" {
+				t.Fatalf("response = %d %s", rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestOpenAISpeed(t *testing.T) {
+	tests := []struct {
+		speed float64
+		want  int
+	}{
+		{0.25, 50},
+		{0.625, 75},
+		{1, 100},
+		{2.5, 150},
+		{4, 200},
+	}
+
+	for _, tt := range tests {
+		if got := openAISpeed(tt.speed); got != tt.want {
+			t.Errorf("openAISpeed(%v) = %d, want %d", tt.speed, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+// Package server exposes VOICEPEAK speech synthesis, narrator listing and
+// dictionary management over HTTP.
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/shinshin86/vpeak"
+)
+
+// Server is an http.Handler serving the following endpoints:
+//
+//	POST   /speech                     synthesize {"text", "narrator", "emotion", "speed", "pitch"} into audio/wav
+//	GET    /narrators                  list installed narrators
+//	GET    /narrators/{name}/emotions  list emotions of a narrator
+//	GET    /dictionary                 list dictionary entries
+//	POST   /dictionary                 add a dictionary entry
+//	PUT    /dictionary/{surface}       replace the entry with the given surface
+//	DELETE /dictionary/{surface}       delete the entry with the given surface
+//
+// VOICEPEAK only handles one job at a time, so calls to it are serialized.
+type Server struct {
+	*backend
+	mux *http.ServeMux
+}
+
+// backend holds the state shared by all server flavours.
+type backend struct {
+	client         *vpeak.Client
+	dictionaryPath string
+
+	// voicepeakMu serializes VOICEPEAK invocations.
+	voicepeakMu sync.Mutex
+	// dictionaryMu serializes dictionary read-modify-write cycles.
+	dictionaryMu sync.Mutex
+}
+
+// SpeechRequest is the body of POST /speech.
+type SpeechRequest struct {
+	Text     string `json:"text"`
+	Narrator string `json:"narrator"`
+	Emotion  string `json:"emotion"`
+	Speed    *int   `json:"speed"`
+	Pitch    *int   `json:"pitch"`
+}
+
+// New returns a server that runs VOICEPEAK through client and edits the
+// dictionary at dictionaryPath. An empty dictionaryPath uses
+// vpeak.DefaultDictionaryPath.
+func New(client *vpeak.Client, dictionaryPath string) *Server {
+	s := &Server{backend: newBackend(client, dictionaryPath), mux: http.NewServeMux()}
+	s.mux.HandleFunc("/speech", s.handleSpeech)
+	s.mux.HandleFunc("/narrators", s.handleNarrators)
+	s.mux.HandleFunc("/narrators/", s.handleEmotions)
+	s.mux.HandleFunc("/dictionary", s.handleDictionary)
+	s.mux.HandleFunc("/dictionary/", s.handleDictionaryWord)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handleSpeech(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	var req SpeechRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("decode request: %w", err))
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("text is required"))
+		return
+	}
+	if req.Speed != nil && (*req.Speed < 50 || *req.Speed > 200) {
+		writeError(w, http.StatusBadRequest, errors.New("speed must be between 50 and 200"))
+		return
+	}
+	if req.Pitch != nil && (*req.Pitch < -300 || *req.Pitch > 300) {
+		writeError(w, http.StatusBadRequest, errors.New("pitch must be between -300 and 300"))
+		return
+	}
+
+	audio, err := s.synthesize(r.Context(), req.Text, vpeak.Options{
+		Narrator: req.Narrator,
+		Emotion:  req.Emotion,
+		Speed:    req.Speed,
+		Pitch:    req.Pitch,
+	})
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "audio/wav")
+	w.Write(audio)
+}
+
+func (s *Server) handleNarrators(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	narrators, err := s.narrators(r.Context())
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nonNil(narrators))
+}
+
+func (s *Server) handleEmotions(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/narrators/"), "/emotions")
+	if !ok || name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	emotions, err := s.emotions(r.Context(), name)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nonNil(emotions))
+}
+
+func (s *Server) handleDictionary(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+
+	path, err := s.resolveDictionaryPath()
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+
+	s.dictionaryMu.Lock()
+	defer s.dictionaryMu.Unlock()
+
+	if r.Method == http.MethodGet {
+		entries, err := vpeak.LoadDictionary(path)
+		if err != nil {
+			writeError(w, statusFor(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, entries)
+		return
+	}
+
+	entry, err := decodeDictEntry(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := vpeak.AddDictionaryWord(path, entry); err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+
+	entry, _ = vpeak.NormalizeDictEntry(entry)
+	writeJSON(w, http.StatusCreated, entry)
+}
+
+func (s *Server) handleDictionaryWord(w http.ResponseWriter, r *http.Request) {
+	surface := strings.TrimPrefix(r.URL.Path, "/dictionary/")
+	if surface == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if !allowMethods(w, r, http.MethodPut, http.MethodDelete) {
+		return
+	}
+
+	path, err := s.resolveDictionaryPath()
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+
+	s.dictionaryMu.Lock()
+	defer s.dictionaryMu.Unlock()
+
+	if r.Method == http.MethodDelete {
+		if err := vpeak.DeleteDictionaryWordBySurface(path, surface); err != nil {
+			writeError(w, statusFor(err), err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	entry, err := decodeDictEntry(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := vpeak.UpdateDictionaryWordBySurface(path, surface, entry); err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+
+	entry, _ = vpeak.NormalizeDictEntry(entry)
+	writeJSON(w, http.StatusOK, entry)
+}
+
+func newBackend(client *vpeak.Client, dictionaryPath string) *backend {
+	return &backend{client: client, dictionaryPath: dictionaryPath}
+}
+
+// synthesize renders text with opts into memory.
+func (b *backend) synthesize(ctx context.Context, text string, opts vpeak.Options) ([]byte, error) {
+	var audio bytes.Buffer
+
+	b.voicepeakMu.Lock()
+	err := b.client.SynthesizeToContext(ctx, &audio, text, opts)
+	b.voicepeakMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return audio.Bytes(), nil
+}
+
+func (b *backend) narrators(ctx context.Context) ([]string, error) {
+	b.voicepeakMu.Lock()
+	defer b.voicepeakMu.Unlock()
+	return b.client.ListNarratorsContext(ctx)
+}
+
+func (b *backend) emotions(ctx context.Context, narrator string) ([]string, error) {
+	b.voicepeakMu.Lock()
+	defer b.voicepeakMu.Unlock()
+	return b.client.ListEmotionsContext(ctx, narrator)
+}
+
+func (b *backend) resolveDictionaryPath() (string, error) {
+	if b.dictionaryPath != "" {
+		return b.dictionaryPath, nil
+	}
+	return vpeak.DefaultDictionaryPath()
+}
+
+func decodeDictEntry(r *http.Request) (vpeak.DictEntry, error) {
+	var entry vpeak.DictEntry
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		return vpeak.DictEntry{}, fmt.Errorf("decode request: %w", err)
+	}
+	return entry, nil
+}
+
+// statusFor maps library errors onto HTTP status codes.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, vpeak.ErrDictionaryWordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, vpeak.ErrDictionaryWordConflict):
+		return http.StatusConflict
+	case errors.Is(err, vpeak.ErrDictionaryWordInvalid), errors.Is(err, vpeak.ErrInvalidEmotion):
+		return http.StatusBadRequest
+	case errors.Is(err, vpeak.ErrVoicepeakNotFound),
+		errors.Is(err, vpeak.ErrUnsupportedPlatform),
+		errors.Is(err, vpeak.ErrDictionaryPathUnsupported):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	return checkMethod(w, r, writeError, methods)
+}
+
+// checkMethod reports whether r uses one of methods, replying with 405 and
+// writeErr otherwise.
+func checkMethod(w http.ResponseWriter, r *http.Request, writeErr func(http.ResponseWriter, int, error), methods []string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeErr(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func nonNil(items []string) []string {
+	if items == nil {
+		return []string{}
+	}
+	return items
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/shinshin86/vpeak"
+	"github.com/shinshin86/vpeak/wav"
+)
+
+type fakeEngine struct {
+	mu    sync.Mutex
+	calls [][]string
+}
+
+func (e *fakeEngine) Run(ctx context.Context, args []string) ([]byte, error) {
+	e.mu.Lock()
+	e.calls = append(e.calls, args)
+	e.mu.Unlock()
+
+	switch args[0] {
+	case "--list-narrator":
+		return []byte("Japanese Female 1\nZundamon\n"), nil
+	case "--list-emotion":
+		return []byte("happy\nsad\n"), nil
+	}
+
+	for i, arg := range args {
+		if arg == "-o" {
+			audio := &wav.Audio{
+				Format: wav.Format{AudioFormat: wav.FormatPCM, Channels: 1, SampleRate: 8000, BitsPerSample: 16},
+				Data:   make([]byte, 1600),
+			}
+			return nil, audio.WriteFile(args[i+1])
+		}
+	}
+	return nil, nil
+}
+
+func newTestServer(t *testing.T) (*Server, *fakeEngine, string) {
+	t.Helper()
+	engine := &fakeEngine{}
+	dictPath := filepath.Join(t.TempDir(), "dic.json")
+	return New(&vpeak.Client{Engine: engine}, dictPath), engine, dictPath
+}
+
+func serve(s http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSpeech(t *testing.T) {
+	s, engine, _ := newTestServer(t)
+
+	rec := serve(s, http.MethodPost, "/speech", `{"text":"こんにちは","narrator":"f1","emotion":"happy=50","speed":120}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "audio/wav" {
+		t.Fatalf("Content-Type = %q, want audio/wav", ct)
+	}
+
+	audio, err := wav.Parse(rec.Body.Bytes())
+	if err != nil {
+		t.Fatalf("wav.Parse() error = %v", err)
+	}
+	if audio.Frames() != 800 {
+		t.Fatalf("Frames() = %d, want 800", audio.Frames())
+	}
+
+	args := strings.Join(engine.calls[0], " ")
+	for _, want := range []string{"--narrator Japanese Female 1", "--emotion happy=50", "--speed 120", "-s こんにちは"} {
+		if !strings.Contains(args, want) {
+			t.Fatalf("args = %q, want %q", args, want)
+		}
+	}
+}
+
+func TestSpeechRejectsInvalidRequests(t *testing.T) {
+	s, engine, _ := newTestServer(t)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"malformed json", `{`},
+		{"missing text", `{"narrator":"f1"}`},
+		{"speed out of range", `{"text":"hi","speed":10}`},
+		{"invalid emotion", `{"text":"hi","emotion":"happy=500"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve(s, http.MethodPost, "/speech", tt.body); rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400; body = %s", rec.Code, rec.Body)
+			}
+		})
+	}
+
+	if rec := serve(s, http.MethodGet, "/speech", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET /speech status = %d, want 405", rec.Code)
+	}
+	if len(engine.calls) != 0 {
+		t.Fatalf("engine calls = %v, want none", engine.calls)
+	}
+}
+
+func TestNarratorsAndEmotions(t *testing.T) {
+	s, engine, _ := newTestServer(t)
+
+	rec := serve(s, http.MethodGet, "/narrators", "")
+	var narrators []string
+	if err := json.Unmarshal(rec.Body.Bytes(), &narrators); err != nil || len(narrators) != 2 || narrators[1] != "Zundamon" {
+		t.Fatalf("GET /narrators = %d %s", rec.Code, rec.Body)
+	}
+
+	rec = serve(s, http.MethodGet, "/narrators/f1/emotions", "")
+	var emotions []string
+	if err := json.Unmarshal(rec.Body.Bytes(), &emotions); err != nil || len(emotions) != 2 {
+		t.Fatalf("GET /narrators/f1/emotions = %d %s", rec.Code, rec.Body)
+	}
+	if got := engine.calls[1]; got[1] != "Japanese Female 1" {
+		t.Fatalf("emotion call = %v, want resolved narrator", got)
+	}
+
+	if rec := serve(s, http.MethodGet, "/narrators/f1/other", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown path status = %d, want 404", rec.Code)
+	}
+}
+
+func TestDictionaryCRUD(t *testing.T) {
+	s, _, dictPath := newTestServer(t)
+
+	entry := `{"sur":"GitHub","pron":"ギットハブ","pos":"Japanese_Koyuumeishi_ippan","priority":5,"accentType":0}`
+	if rec := serve(s, http.MethodPost, "/dictionary", entry); rec.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if rec := serve(s, http.MethodPost, "/dictionary", entry); rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate POST status = %d, want 409", rec.Code)
+	}
+
+	updated := `{"sur":"GitHub Actions","pron":"ギットハブアクションズ","pos":"Japanese_Koyuumeishi_ippan","priority":5,"accentType":3}`
+	if rec := serve(s, http.MethodPut, "/dictionary/GitHub", updated); rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	rec := serve(s, http.MethodGet, "/dictionary", "")
+	var entries []vpeak.DictEntry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil || len(entries) != 1 || entries[0].Surface != "GitHub Actions" {
+		t.Fatalf("GET /dictionary = %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := serve(s, http.MethodDelete, "/dictionary/GitHub%20Actions", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if rec := serve(s, http.MethodDelete, "/dictionary/GitHub%20Actions", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("second DELETE status = %d, want 404", rec.Code)
+	}
+
+	data, err := os.ReadFile(dictPath)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if !bytes.Equal(bytes.TrimSpace(data), []byte("[]")) {
+		t.Fatalf("dictionary = %s, want empty", data)
+	}
+}
@@ -0,0 +1,570 @@
+package server
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/shinshin86/vpeak"
+	"github.com/shinshin86/vpeak/wav"
+)
+
+// VoicevoxVersion is the VOICEVOX engine version reported by GET /version.
+const VoicevoxVersion = "0.14.0"
+
+// naturalStyle is the style name used for a narrator without emotion.
+const naturalStyle = "natural"
+
+// maxVoicevoxSamplingRate bounds the outputSamplingRate /synthesis converts to.
+const maxVoicevoxSamplingRate = 192000
+
+// voicevoxWordTypes maps the VOICEVOX word types VOICEPEAK can represent
+// onto dictionary parts of speech.
+var voicevoxWordTypes = map[string]string{
+	"PROPER_NOUN": "Japanese_Koyuumeishi_ippan",
+	"COMMON_NOUN": "Japanese_Futsuu_meishi",
+}
+
+// VoicevoxServer is an http.Handler implementing the subset of the VOICEVOX
+// engine API used by common clients, so that they can talk to VOICEPEAK
+// unchanged:
+//
+//	GET    /version
+//	GET    /speakers
+//	GET    /is_initialized_speaker, POST /initialize_speaker
+//	POST   /audio_query?text=...&speaker=ID
+//	POST   /synthesis?speaker=ID
+//	GET    /user_dict
+//	POST   /user_dict_word?surface=...&pronunciation=...&accent_type=N
+//	PUT    /user_dict_word/{uuid}?surface=...&pronunciation=...&accent_type=N
+//	DELETE /user_dict_word/{uuid}
+//
+// VOICEPEAK narrators become VOICEVOX speakers and their emotions become
+// styles. Every narrator also gets a "natural" style without emotion.
+// User dictionary words map to vpeak.DictEntry values and are identified by
+// a UUID derived from their surface.
+type VoicevoxServer struct {
+	*backend
+	mux *http.ServeMux
+
+	stylesMu sync.Mutex
+	speakers []voicevoxSpeaker
+	styles   map[int]voicevoxStyleRef
+}
+
+type voicevoxSpeaker struct {
+	Name        string          `json:"name"`
+	SpeakerUUID string          `json:"speaker_uuid"`
+	Styles      []voicevoxStyle `json:"styles"`
+	Version     string          `json:"version"`
+}
+
+type voicevoxStyle struct {
+	Name string `json:"name"`
+	ID   int    `json:"id"`
+}
+
+// voicevoxStyleRef is the VOICEPEAK narrator and emotion behind a style ID.
+type voicevoxStyleRef struct {
+	narrator string
+	emotion  string
+}
+
+// AudioQuery is the VOICEVOX synthesis query. VOICEPEAK does not expose
+// accent phrases, so AccentPhrases is always empty and the text to speak is
+// carried in Kana.
+type AudioQuery struct {
+	AccentPhrases      []json.RawMessage `json:"accent_phrases"`
+	SpeedScale         float64           `json:"speedScale"`
+	PitchScale         float64           `json:"pitchScale"`
+	IntonationScale    float64           `json:"intonationScale"`
+	VolumeScale        float64           `json:"volumeScale"`
+	PrePhonemeLength   float64           `json:"prePhonemeLength"`
+	PostPhonemeLength  float64           `json:"postPhonemeLength"`
+	OutputSamplingRate int               `json:"outputSamplingRate"`
+	OutputStereo       bool              `json:"outputStereo"`
+	Kana               string            `json:"kana"`
+}
+
+// UserDictWord is a VOICEVOX user dictionary word.
+type UserDictWord struct {
+	Surface               string `json:"surface"`
+	Priority              int    `json:"priority"`
+	ContextID             int    `json:"context_id"`
+	PartOfSpeech          string `json:"part_of_speech"`
+	PartOfSpeechDetail1   string `json:"part_of_speech_detail_1"`
+	PartOfSpeechDetail2   string `json:"part_of_speech_detail_2"`
+	PartOfSpeechDetail3   string `json:"part_of_speech_detail_3"`
+	InflectionalType      string `json:"inflectional_type"`
+	InflectionalForm      string `json:"inflectional_form"`
+	Stem                  string `json:"stem"`
+	Yomi                  string `json:"yomi"`
+	Pronunciation         string `json:"pronunciation"`
+	AccentType            int    `json:"accent_type"`
+	MoraCount             int    `json:"mora_count"`
+	AccentAssociativeRule string `json:"accent_associative_rule"`
+}
+
+// NewVoicevox returns a VOICEVOX-compatible server that runs VOICEPEAK through
+// client and edits the dictionary at dictionaryPath. An empty dictionaryPath
+// uses vpeak.DefaultDictionaryPath.
+func NewVoicevox(client *vpeak.Client, dictionaryPath string) *VoicevoxServer {
+	s := &VoicevoxServer{backend: newBackend(client, dictionaryPath), mux: http.NewServeMux()}
+	s.mux.HandleFunc("/version", s.handleVersion)
+	s.mux.HandleFunc("/speakers", s.handleSpeakers)
+	s.mux.HandleFunc("/is_initialized_speaker", s.handleIsInitializedSpeaker)
+	s.mux.HandleFunc("/initialize_speaker", s.handleInitializeSpeaker)
+	s.mux.HandleFunc("/audio_query", s.handleAudioQuery)
+	s.mux.HandleFunc("/synthesis", s.handleSynthesis)
+	s.mux.HandleFunc("/user_dict", s.handleUserDict)
+	s.mux.HandleFunc("/user_dict_word", s.handleAddUserDictWord)
+	s.mux.HandleFunc("/user_dict_word/", s.handleUserDictWord)
+	return s
+}
+
+func (s *VoicevoxServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *VoicevoxServer) handleVersion(w http.ResponseWriter, r *http.Request) {
+	if !allowVoicevoxMethods(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, http.StatusOK, VoicevoxVersion)
+}
+
+func (s *VoicevoxServer) handleSpeakers(w http.ResponseWriter, r *http.Request) {
+	if !allowVoicevoxMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	speakers, _, err := s.loadStyles(r.Context())
+	if err != nil {
+		writeVoicevoxError(w, statusForVoicevox(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, speakers)
+}
+
+func (s *VoicevoxServer) handleIsInitializedSpeaker(w http.ResponseWriter, r *http.Request) {
+	if !allowVoicevoxMethods(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, http.StatusOK, true)
+}
+
+func (s *VoicevoxServer) handleInitializeSpeaker(w http.ResponseWriter, r *http.Request) {
+	if !allowVoicevoxMethods(w, r, http.MethodPost) {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *VoicevoxServer) handleAudioQuery(w http.ResponseWriter, r *http.Request) {
+	if !allowVoicevoxMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	text := r.URL.Query().Get("text")
+	if strings.TrimSpace(text) == "" {
+		writeVoicevoxError(w, http.StatusUnprocessableEntity, errors.New("text is required"))
+		return
+	}
+	if _, err := s.resolveStyle(r); err != nil {
+		writeVoicevoxError(w, statusForVoicevox(err), err)
+		return
+	}
+
+	// An outputSamplingRate of 0 keeps VOICEPEAK's own rate, so /synthesis
+	// only resamples when the client asks for another one.
+	writeJSON(w, http.StatusOK, AudioQuery{
+		AccentPhrases:      []json.RawMessage{},
+		SpeedScale:         1,
+		PitchScale:         0,
+		IntonationScale:    1,
+		VolumeScale:        1,
+		PrePhonemeLength:   0.1,
+		PostPhonemeLength:  0.1,
+		OutputSamplingRate: 0,
+		Kana:               text,
+	})
+}
+
+func (s *VoicevoxServer) handleSynthesis(w http.ResponseWriter, r *http.Request) {
+	if !allowVoicevoxMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	style, err := s.resolveStyle(r)
+	if err != nil {
+		writeVoicevoxError(w, statusForVoicevox(err), err)
+		return
+	}
+
+	var query AudioQuery
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		writeVoicevoxError(w, http.StatusUnprocessableEntity, fmt.Errorf("decode audio query: %w", err))
+		return
+	}
+	if strings.TrimSpace(query.Kana) == "" {
+		writeVoicevoxError(w, http.StatusUnprocessableEntity, errors.New("kana is empty; create the query with /audio_query"))
+		return
+	}
+	if query.OutputSamplingRate < 0 || query.OutputSamplingRate > maxVoicevoxSamplingRate {
+		writeVoicevoxError(w, http.StatusUnprocessableEntity, fmt.Errorf("outputSamplingRate must be 0 (keep VOICEPEAK's rate) or between 1 and %d", maxVoicevoxSamplingRate))
+		return
+	}
+
+	opts := vpeak.Options{Narrator: style.narrator, Emotion: style.emotion}
+	if query.SpeedScale != 0 && query.SpeedScale != 1 {
+		speed := clampInt(int(math.Round(query.SpeedScale*100)), 50, 200)
+		opts.Speed = &speed
+	}
+	if query.PitchScale != 0 {
+		// VOICEVOX pitchScale spans about ±0.15; VOICEPEAK pitch spans ±300.
+		pitch := clampInt(int(math.Round(query.PitchScale*2000)), -300, 300)
+		opts.Pitch = &pitch
+	}
+
+	audio, err := s.synthesize(r.Context(), query.Kana, opts)
+	if err != nil {
+		writeVoicevoxError(w, statusForVoicevox(err), err)
+		return
+	}
+
+	if audio, err = convertVoicevoxAudio(audio, query); err != nil {
+		writeVoicevoxError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "audio/wav")
+	w.Write(audio)
+}
+
+// convertVoicevoxAudio converts a rendered WAV to the sampling rate and
+// channel count requested by query. A zero outputSamplingRate keeps
+// VOICEPEAK's rate, and audio already in the requested format is returned
+// unchanged.
+func convertVoicevoxAudio(audio []byte, query AudioQuery) ([]byte, error) {
+	channels := uint16(1)
+	if query.OutputStereo {
+		channels = 2
+	}
+
+	clip, err := wav.Parse(audio)
+	if err != nil {
+		return nil, err
+	}
+	sampleRate := clip.Format.SampleRate
+	if query.OutputSamplingRate > 0 {
+		sampleRate = uint32(query.OutputSamplingRate)
+	}
+	if clip.Format.SampleRate == sampleRate && clip.Format.Channels == channels {
+		return audio, nil
+	}
+	if clip, err = clip.Convert(channels, sampleRate); err != nil {
+		return nil, err
+	}
+	return clip.Bytes(), nil
+}
+
+func (s *VoicevoxServer) handleUserDict(w http.ResponseWriter, r *http.Request) {
+	if !allowVoicevoxMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	path, err := s.resolveDictionaryPath()
+	if err != nil {
+		writeVoicevoxError(w, statusForVoicevox(err), err)
+		return
+	}
+
+	s.dictionaryMu.Lock()
+	entries, err := vpeak.LoadDictionary(path)
+	s.dictionaryMu.Unlock()
+	if err != nil {
+		writeVoicevoxError(w, statusForVoicevox(err), err)
+		return
+	}
+
+	words := make(map[string]UserDictWord, len(entries))
+	for _, entry := range entries {
+		words[wordUUID(entry.Surface)] = userDictWordFromEntry(entry)
+	}
+	writeJSON(w, http.StatusOK, words)
+}
+
+func (s *VoicevoxServer) handleAddUserDictWord(w http.ResponseWriter, r *http.Request) {
+	if !allowVoicevoxMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	entry, err := dictEntryFromQuery(r)
+	if err == nil {
+		entry, err = vpeak.NormalizeDictEntry(entry)
+	}
+	if err != nil {
+		writeVoicevoxError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	path, err := s.resolveDictionaryPath()
+	if err != nil {
+		writeVoicevoxError(w, statusForVoicevox(err), err)
+		return
+	}
+
+	s.dictionaryMu.Lock()
+	err = vpeak.AddDictionaryWord(path, entry)
+	s.dictionaryMu.Unlock()
+	if err != nil {
+		writeVoicevoxError(w, statusForVoicevox(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, wordUUID(entry.Surface))
+}
+
+func (s *VoicevoxServer) handleUserDictWord(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/user_dict_word/")
+	if id == "" || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if !allowVoicevoxMethods(w, r, http.MethodPut, http.MethodDelete) {
+		return
+	}
+
+	var next vpeak.DictEntry
+	if r.Method == http.MethodPut {
+		var err error
+		next, err = dictEntryFromQuery(r)
+		if err != nil {
+			writeVoicevoxError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+	}
+
+	path, err := s.resolveDictionaryPath()
+	if err != nil {
+		writeVoicevoxError(w, statusForVoicevox(err), err)
+		return
+	}
+
+	s.dictionaryMu.Lock()
+	defer s.dictionaryMu.Unlock()
+
+	entries, err := vpeak.LoadDictionary(path)
+	if err != nil {
+		writeVoicevoxError(w, statusForVoicevox(err), err)
+		return
+	}
+
+	surface := ""
+	for _, entry := range entries {
+		if wordUUID(entry.Surface) == id {
+			surface = entry.Surface
+			break
+		}
+	}
+	if surface == "" {
+		writeVoicevoxError(w, http.StatusNotFound, fmt.Errorf("%w: uuid %s", vpeak.ErrDictionaryWordNotFound, id))
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		err = vpeak.DeleteDictionaryWordBySurface(path, surface)
+	} else {
+		err = vpeak.UpdateDictionaryWordBySurface(path, surface, next)
+	}
+	if err != nil {
+		writeVoicevoxError(w, statusForVoicevox(err), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadStyles lists narrators and their emotions once and assigns style IDs
+// in listing order.
+func (s *VoicevoxServer) loadStyles(ctx context.Context) ([]voicevoxSpeaker, map[int]voicevoxStyleRef, error) {
+	s.stylesMu.Lock()
+	defer s.stylesMu.Unlock()
+
+	if s.speakers != nil {
+		return s.speakers, s.styles, nil
+	}
+
+	narrators, err := s.narrators(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	speakers := make([]voicevoxSpeaker, 0, len(narrators))
+	styles := map[int]voicevoxStyleRef{}
+	for _, narrator := range narrators {
+		emotions, err := s.emotions(ctx, narrator)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		speaker := voicevoxSpeaker{Name: narrator, SpeakerUUID: nameUUID("speaker", narrator), Version: VoicevoxVersion}
+		for _, emotion := range append([]string{""}, emotions...) {
+			id := len(styles)
+			name := emotion
+			if name == "" {
+				name = naturalStyle
+			}
+			speaker.Styles = append(speaker.Styles, voicevoxStyle{Name: name, ID: id})
+			styles[id] = voicevoxStyleRef{narrator: narrator, emotion: emotion}
+		}
+		speakers = append(speakers, speaker)
+	}
+
+	s.speakers, s.styles = speakers, styles
+	return speakers, styles, nil
+}
+
+// errUnknownStyle reports a speaker query parameter that is not a style ID.
+var errUnknownStyle = errors.New("unknown speaker style")
+
+func (s *VoicevoxServer) resolveStyle(r *http.Request) (voicevoxStyleRef, error) {
+	raw := r.URL.Query().Get("speaker")
+	id, err := strconv.Atoi(raw)
+	if err != nil {
+		return voicevoxStyleRef{}, fmt.Errorf("%w: %q", errUnknownStyle, raw)
+	}
+
+	_, styles, err := s.loadStyles(r.Context())
+	if err != nil {
+		return voicevoxStyleRef{}, err
+	}
+
+	style, ok := styles[id]
+	if !ok {
+		return voicevoxStyleRef{}, fmt.Errorf("%w: %d", errUnknownStyle, id)
+	}
+	return style, nil
+}
+
+func dictEntryFromQuery(r *http.Request) (vpeak.DictEntry, error) {
+	query := r.URL.Query()
+
+	accentType, err := strconv.Atoi(query.Get("accent_type"))
+	if err != nil {
+		return vpeak.DictEntry{}, fmt.Errorf("invalid accent_type: %q", query.Get("accent_type"))
+	}
+
+	priority := 5
+	if raw := query.Get("priority"); raw != "" {
+		priority, err = strconv.Atoi(raw)
+		if err != nil {
+			return vpeak.DictEntry{}, fmt.Errorf("invalid priority: %q", raw)
+		}
+	}
+
+	wordType := query.Get("word_type")
+	if wordType == "" {
+		wordType = "PROPER_NOUN"
+	}
+	pos, ok := voicevoxWordTypes[wordType]
+	if !ok {
+		return vpeak.DictEntry{}, fmt.Errorf("word_type %q is not supported by VOICEPEAK", wordType)
+	}
+
+	return vpeak.DictEntry{
+		Surface:       query.Get("surface"),
+		Pronunciation: query.Get("pronunciation"),
+		Pos:           pos,
+		Priority:      priority,
+		AccentType:    accentType,
+	}, nil
+}
+
+func userDictWordFromEntry(entry vpeak.DictEntry) UserDictWord {
+	detail1 := "固有名詞"
+	if entry.Pos == "Japanese_Futsuu_meishi" {
+		detail1 = "一般"
+	}
+
+	return UserDictWord{
+		Surface:               entry.Surface,
+		Priority:              entry.Priority,
+		PartOfSpeech:          "名詞",
+		PartOfSpeechDetail1:   detail1,
+		PartOfSpeechDetail2:   "*",
+		PartOfSpeechDetail3:   "*",
+		InflectionalType:      "*",
+		InflectionalForm:      "*",
+		Stem:                  "*",
+		Yomi:                  entry.Pronunciation,
+		Pronunciation:         entry.Pronunciation,
+		AccentType:            entry.AccentType,
+		MoraCount:             moraCount(entry.Pronunciation),
+		AccentAssociativeRule: "*",
+	}
+}
+
+// moraCount counts the morae of a katakana pronunciation; small kana other
+// than ッ attach to the preceding mora.
+func moraCount(pronunciation string) int {
+	count := 0
+	for _, r := range pronunciation {
+		if !strings.ContainsRune("ァィゥェォャュョヮ", r) {
+			count++
+		}
+	}
+	return count
+}
+
+// wordUUID returns the identifier of the dictionary word with the given
+// normalized surface.
+func wordUUID(surface string) string {
+	return nameUUID("user_dict_word", surface)
+}
+
+// nameUUID derives a stable version 5 style UUID from kind and name.
+func nameUUID(kind, name string) string {
+	sum := sha1.Sum([]byte("vpeak/" + kind + "/" + name))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+func clampInt(n, min, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// statusForVoicevox is statusFor with VOICEVOX's use of 422 for invalid input.
+func statusForVoicevox(err error) int {
+	if errors.Is(err, errUnknownStyle) {
+		return http.StatusUnprocessableEntity
+	}
+	status := statusFor(err)
+	if status == http.StatusBadRequest || status == http.StatusConflict {
+		return http.StatusUnprocessableEntity
+	}
+	return status
+}
+
+func allowVoicevoxMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	return checkMethod(w, r, writeVoicevoxError, methods)
+}
+
+// writeVoicevoxError writes err in the FastAPI error shape used by VOICEVOX.
+func writeVoicevoxError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"detail": err.Error()})
+}
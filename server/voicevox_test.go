@@ -0,0 +1,134 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/shinshin86/vpeak"
+	"github.com/shinshin86/vpeak/wav"
+)
+
+func newTestVoicevoxServer(t *testing.T) (*VoicevoxServer, *fakeEngine) {
+	t.Helper()
+	_, engine, dictPath := newTestServer(t)
+	return NewVoicevox(&vpeak.Client{Engine: engine}, dictPath), engine
+}
+
+func TestVoicevoxSpeakers(t *testing.T) {
+	s, _ := newTestVoicevoxServer(t)
+
+	rec := serve(s, http.MethodGet, "/speakers", "")
+	var speakers []voicevoxSpeaker
+	if err := json.Unmarshal(rec.Body.Bytes(), &speakers); err != nil {
+		t.Fatalf("GET /speakers = %d %s", rec.Code, rec.Body)
+	}
+
+	if len(speakers) != 2 || speakers[1].Name != "Zundamon" {
+		t.Fatalf("speakers = %+v", speakers)
+	}
+	want := []voicevoxStyle{{"natural", 3}, {"happy", 4}, {"sad", 5}}
+	for i, style := range want {
+		if speakers[1].Styles[i] != style {
+			t.Fatalf("Zundamon styles = %+v, want %+v", speakers[1].Styles, want)
+		}
+	}
+	if speakers[0].SpeakerUUID == speakers[1].SpeakerUUID {
+		t.Fatal("speaker UUIDs are not unique")
+	}
+}
+
+func TestVoicevoxAudioQueryAndSynthesis(t *testing.T) {
+	s, engine := newTestVoicevoxServer(t)
+
+	rec := serve(s, http.MethodPost, "/audio_query?speaker=4&text="+url.QueryEscape("こんにちは"), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /audio_query = %d %s", rec.Code, rec.Body)
+	}
+
+	var query AudioQuery
+	if err := json.Unmarshal(rec.Body.Bytes(), &query); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	query.SpeedScale = 1.2
+	query.PitchScale = 0.05
+	body, _ := json.Marshal(query)
+
+	rec = serve(s, http.MethodPost, "/synthesis?speaker=4", string(body))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "audio/wav" {
+		t.Fatalf("POST /synthesis = %d %s", rec.Code, rec.Body)
+	}
+
+	clip, err := wav.Read(rec.Body)
+	if err != nil {
+		t.Fatalf("wav.Read() error = %v", err)
+	}
+	if query.OutputSamplingRate != 0 || clip.Format.SampleRate != 8000 || clip.Format.Channels != 1 {
+		t.Fatalf("synthesis format = %s for outputSamplingRate %d, want VOICEPEAK's 8000 Hz mono", clip.Format, query.OutputSamplingRate)
+	}
+
+	args := strings.Join(engine.calls[len(engine.calls)-1], " ")
+	for _, want := range []string{"--narrator Zundamon", "--emotion happy=100", "--speed 120", "--pitch 100", "-s こんにちは"} {
+		if !strings.Contains(args, want) {
+			t.Fatalf("args = %q, want %q", args, want)
+		}
+	}
+
+	query.OutputSamplingRate = 48000
+	query.OutputStereo = true
+	stereo, _ := json.Marshal(query)
+	rec = serve(s, http.MethodPost, "/synthesis?speaker=4", string(stereo))
+	if clip, err := wav.Read(rec.Body); err != nil || clip.Format.SampleRate != 48000 || clip.Format.Channels != 2 {
+		t.Fatalf("stereo synthesis = %v, %v; want 48000 Hz stereo", clip, err)
+	}
+
+	query.OutputSamplingRate = -1
+	invalid, _ := json.Marshal(query)
+	if rec := serve(s, http.MethodPost, "/synthesis?speaker=4", string(invalid)); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid outputSamplingRate status = %d, want 422", rec.Code)
+	}
+
+	if rec := serve(s, http.MethodPost, "/synthesis?speaker=99", string(body)); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unknown speaker status = %d, want 422", rec.Code)
+	}
+}
+
+func TestVoicevoxUserDict(t *testing.T) {
+	s, _ := newTestVoicevoxServer(t)
+
+	add := "/user_dict_word?surface=" + url.QueryEscape("ＧｉｔＨｕｂ") +
+		"&pronunciation=" + url.QueryEscape("ギットハブ") + "&accent_type=1&word_type=COMMON_NOUN"
+	rec := serve(s, http.MethodPost, add, "")
+	var id string
+	if err := json.Unmarshal(rec.Body.Bytes(), &id); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("POST /user_dict_word = %d %s", rec.Code, rec.Body)
+	}
+
+	rec = serve(s, http.MethodGet, "/user_dict", "")
+	var words map[string]UserDictWord
+	if err := json.Unmarshal(rec.Body.Bytes(), &words); err != nil {
+		t.Fatalf("GET /user_dict = %d %s", rec.Code, rec.Body)
+	}
+	word, ok := words[id]
+	if !ok || word.Surface != "GitHub" || word.Pronunciation != "ギットハブ" || word.MoraCount != 5 || word.AccentType != 1 {
+		t.Fatalf("user_dict = %+v, want GitHub under %s", words, id)
+	}
+
+	update := "/user_dict_word/" + id + "?surface=GitHub&pronunciation=" + url.QueryEscape("ギットハブ") + "&accent_type=2"
+	if rec := serve(s, http.MethodPut, update, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("PUT = %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := serve(s, http.MethodDelete, "/user_dict_word/"+id, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE = %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(s, http.MethodDelete, "/user_dict_word/"+id, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("second DELETE = %d, want 404", rec.Code)
+	}
+
+	if rec := serve(s, http.MethodPost, "/user_dict_word?surface=x&pronunciation=x&accent_type=0", ""); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid word status = %d, want 422", rec.Code)
+	}
+}
@@ -0,0 +1,104 @@
+package vpeak
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Subtitle formats accepted by Options.Subtitles.
+const (
+	SubtitleSRT = "srt"
+	SubtitleVTT = "vtt"
+)
+
+var ErrInvalidSubtitleFormat = errors.New("invalid subtitle format")
+
+// Cue is one caption with its position in the rendered audio.
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// WriteSRT writes cues in SubRip format.
+func WriteSRT(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	for i, cue := range cues {
+		fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n\n", i+1,
+			formatSubtitleTime(cue.Start, ","), formatSubtitleTime(cue.End, ","), cue.Text)
+	}
+	return bw.Flush()
+}
+
+// WriteVTT writes cues in WebVTT format.
+func WriteVTT(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("WEBVTT\n\n")
+	for _, cue := range cues {
+		fmt.Fprintf(bw, "%s --> %s\n%s\n\n",
+			formatSubtitleTime(cue.Start, "."), formatSubtitleTime(cue.End, "."), cue.Text)
+	}
+	return bw.Flush()
+}
+
+func formatSubtitleTime(d time.Duration, separator string) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, separator, ms%1000)
+}
+
+// parseSubtitleFormats parses a comma-separated list such as "srt,vtt".
+func parseSubtitleFormats(s string) ([]string, error) {
+	var formats []string
+	for _, format := range strings.Split(s, ",") {
+		format = strings.ToLower(strings.TrimSpace(format))
+		switch format {
+		case "":
+		case SubtitleSRT, SubtitleVTT:
+			formats = append(formats, format)
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSubtitleFormat, format)
+		}
+	}
+	return formats, nil
+}
+
+// writeSubtitles writes cues next to the audio file at output, replacing its
+// extension with each format's.
+func writeSubtitles(output string, formats []string, cues []Cue) error {
+	base := strings.TrimSuffix(output, filepath.Ext(output))
+	for _, format := range formats {
+		file, err := os.Create(base + "." + format)
+		if err != nil {
+			return fmt.Errorf("create subtitles: %w", err)
+		}
+
+		if format == SubtitleSRT {
+			err = WriteSRT(file, cues)
+		} else {
+			err = WriteVTT(file, cues)
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("write subtitles: %w", err)
+		}
+	}
+	return nil
+}
+
+// splitSubtitleText splits text into one piece per sentence, further
+// splitting sentences longer than maxLen.
+func splitSubtitleText(text string, maxLen int) []string {
+	var pieces []string
+	for _, sentence := range splitSentences(text) {
+		pieces = append(pieces, SplitText(sentence, maxLen)...)
+	}
+	return pieces
+}
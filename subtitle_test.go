@@ -0,0 +1,88 @@
+package vpeak
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteSRT(t *testing.T) {
+	cues := []Cue{
+		{Start: 0, End: 1500 * time.Millisecond, Text: "こんにちは。"},
+		{Start: time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond, End: time.Hour + 2*time.Minute + 5*time.Second, Text: "さようなら。"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSRT(&buf, cues); err != nil {
+		t.Fatalf("WriteSRT() error = %v", err)
+	}
+
+	want := "1\n00:00:00,000 --> 00:00:01,500\nこんにちは。\n\n" +
+		"2\n01:02:03,004 --> 01:02:05,000\nさようなら。\n\n"
+	if buf.String() != want {
+		t.Fatalf("WriteSRT() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteVTT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteVTT(&buf, []Cue{{Start: 250 * time.Millisecond, End: time.Second, Text: "はい。"}}); err != nil {
+		t.Fatalf("WriteVTT() error = %v", err)
+	}
+
+	want := "WEBVTT\n\n00:00:00.250 --> 00:00:01.000\nはい。\n\n"
+	if buf.String() != want {
+		t.Fatalf("WriteVTT() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestClientGenerateSpeechSubtitles(t *testing.T) {
+	engine := &fakeEngine{audio: testWAV(8000)}
+	client := &Client{Engine: engine}
+	dir := t.TempDir()
+	output := filepath.Join(dir, "speech.wav")
+
+	err := client.GenerateSpeech("おはよう。\nいい天気ですね。", Options{
+		Output:     output,
+		Silent:     true,
+		ChunkPause: 500 * time.Millisecond,
+		Subtitles:  "srt,vtt",
+	})
+	if err != nil {
+		t.Fatalf("GenerateSpeech() error = %v", err)
+	}
+	if len(engine.calls) != 2 {
+		t.Fatalf("engine calls = %d, want one per sentence", len(engine.calls))
+	}
+
+	srt, err := os.ReadFile(filepath.Join(dir, "speech.srt"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	want := "1\n00:00:00,000 --> 00:00:01,000\nおはよう。\n\n" +
+		"2\n00:00:01,500 --> 00:00:02,500\nいい天気ですね。\n\n"
+	if string(srt) != want {
+		t.Fatalf("srt = %q, want %q", srt, want)
+	}
+
+	vtt, err := os.ReadFile(filepath.Join(dir, "speech.vtt"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if !strings.HasPrefix(string(vtt), "WEBVTT\n") {
+		t.Fatalf("vtt = %q, want WEBVTT header", vtt)
+	}
+}
+
+func TestClientGenerateSpeechInvalidSubtitles(t *testing.T) {
+	client := &Client{Engine: &fakeEngine{}}
+
+	err := client.GenerateSpeech("hi", Options{Silent: true, Subtitles: "ass"})
+	if !errors.Is(err, ErrInvalidSubtitleFormat) {
+		t.Fatalf("GenerateSpeech() error = %v, want ErrInvalidSubtitleFormat", err)
+	}
+}
@@ -2,43 +2,32 @@ package vpeak
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"os"
+	"io"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var VoicepeakPath string
 
 func init() {
-	if runtime.GOOS == "darwin" {
-		VoicepeakPath = "/Applications/voicepeak.app/Contents/MacOS/voicepeak"
-	} else if runtime.GOOS == "windows" {
-		VoicepeakPath = "C:\\Program Files\\VOICEPEAK\\voicepeak.exe"
-	} else {
-		log.Fatal("Unsupported operating system")
-	}
+	VoicepeakPath = defaultVoicepeakPath()
 }
 
-const (
-	WavName = "output.wav"
+var (
+	ErrVoicepeakNotFound   = errors.New("voicepeak executable not found")
+	ErrUnsupportedPlatform = errors.New("unsupported operating system")
+	ErrInvalidEmotion      = errors.New("invalid emotion")
 )
 
-var (
-	narratorMap = map[string]string{
-		"f1": "Japanese Female 1",
-		"f2": "Japanese Female 2",
-		"f3": "Japanese Female 3",
-		"m1": "Japanese Male 1",
-		"m2": "Japanese Male 2",
-		"m3": "Japanese Male 3",
-		"c":  "Japanese Female Child",
-	}
+const (
+	WavName = "output.wav"
 )
 
 // Options struct holds the settings for speech generation
@@ -49,6 +38,88 @@ type Options struct {
 	Silent   bool
 	Speed    *int
 	Pitch    *int
+	// MaxChunkLength is the maximum number of characters sent to VOICEPEAK
+	// in one call. Longer text is split and the pieces are joined into
+	// Output. Zero uses DefaultMaxChunkLength.
+	MaxChunkLength int
+	// ChunkPause is the silence inserted between split pieces. It must not be
+	// negative.
+	ChunkPause time.Duration
+	// Subtitles lists the caption files to write next to Output, as a
+	// comma-separated list of SubtitleSRT and SubtitleVTT. Each sentence is
+	// then synthesized separately to time its cue.
+	Subtitles string
+	// Format is the format of the text: FormatText (the default) or
+	// FormatMarkdown, which speaks the prose of a Markdown document.
+	Format string
+	// HeadingPause is the silence inserted before each Markdown heading. Zero
+	// uses DefaultHeadingPause; it must not be negative.
+	HeadingPause time.Duration
+	// SplitSections renders each Markdown heading section into its own file,
+	// named after Output with a -01, -02, ... suffix.
+	SplitSections bool
+	// Workers is the number of files ProcessTextFiles renders at the same
+	// time, each in its own VOICEPEAK process. Zero or less renders one file
+	// at a time.
+	Workers int
+	// Recursive makes ProcessTextFiles descend into subdirectories, mirroring
+	// their layout in the output directory.
+	Recursive bool
+	// Extensions lists the file extensions ProcessTextFiles reads, such as
+	// ".txt". If empty, only .txt files are read.
+	Extensions []string
+	// Include and Exclude are glob patterns (see path.Match) that select the
+	// files ProcessTextFiles reads. Patterns containing a slash match the
+	// path relative to the input directory, others match the base name. An
+	// excluded directory is skipped entirely.
+	Include []string
+	Exclude []string
+	// Resume makes ProcessTextFiles skip files that its manifest records as
+	// rendered from the same contents and options into an existing output.
+	Resume bool
+	// Force makes ProcessTextFiles render every file even if Resume is set.
+	Force bool
+
+	// Profile names a profile of the config file (see LoadProfile) whose
+	// values are used for the fields left empty.
+	Profile string
+	// Strict checks Narrator and Emotion against the narrators and emotions
+	// VOICEPEAK lists before rendering, returning ErrUnknownNarrator or
+	// ErrInvalidEmotion. Set Client.Catalog to avoid listing them each time.
+	Strict bool
+}
+
+// withDefaults returns o with the fields it leaves empty taken from
+// defaults. Flags such as Silent are never taken from defaults.
+func (o Options) withDefaults(defaults Options) Options {
+	if o.Narrator == "" {
+		o.Narrator = defaults.Narrator
+	}
+	if o.Emotion == "" {
+		o.Emotion = defaults.Emotion
+	}
+	if o.Speed == nil {
+		o.Speed = defaults.Speed
+	}
+	if o.Pitch == nil {
+		o.Pitch = defaults.Pitch
+	}
+	if o.MaxChunkLength == 0 {
+		o.MaxChunkLength = defaults.MaxChunkLength
+	}
+	if o.ChunkPause == 0 {
+		o.ChunkPause = defaults.ChunkPause
+	}
+	if o.HeadingPause == 0 {
+		o.HeadingPause = defaults.HeadingPause
+	}
+	if o.Subtitles == "" {
+		o.Subtitles = defaults.Subtitles
+	}
+	if o.Format == "" {
+		o.Format = defaults.Format
+	}
+	return o
 }
 
 type Emotion struct {
@@ -81,86 +152,51 @@ func (e Emotion) IsZero() bool {
 
 // GenerateSpeech generates speech audio from the given text and options
 func GenerateSpeech(text string, opts Options) error {
-	options := buildOptions(text, opts)
-	output := opts.Output
-	if output == "" {
-		output = WavName
-	}
+	return DefaultClient.GenerateSpeech(text, opts)
+}
 
-	cmd1 := vpCmd(options)
-	if err := cmd1.Run(); err != nil {
-		return fmt.Errorf("voicepeak command failed: %w "+
-			"(check that the specified narrator and emotion names are supported by VOICEPEAK)", err)
-	}
+// GenerateSpeechContext is like GenerateSpeech but stops VOICEPEAK and the
+// audio player when ctx is done.
+func GenerateSpeechContext(ctx context.Context, text string, opts Options) error {
+	return DefaultClient.GenerateSpeechContext(ctx, text, opts)
+}
 
-	if !opts.Silent {
-		if err := PlayAudio(output); err != nil {
-			return err
-		}
+// SynthesizeTo writes the WAV audio of text to w. See Client.SynthesizeTo.
+func SynthesizeTo(w io.Writer, text string, opts Options) error {
+	return DefaultClient.SynthesizeTo(w, text, opts)
+}
 
-		// if the output is not specified, delete the generated wav file
-		if runtime.GOOS != "windows" && output == WavName {
-			if err := os.Remove(WavName); err != nil {
-				return fmt.Errorf("failed to delete %s: %v", WavName, err)
-			}
-		}
-	}
+// SynthesizeToContext is like SynthesizeTo but stops VOICEPEAK when ctx is
+// done.
+func SynthesizeToContext(ctx context.Context, w io.Writer, text string, opts Options) error {
+	return DefaultClient.SynthesizeToContext(ctx, w, text, opts)
+}
 
-	return nil
+// Synthesize renders text into memory with DefaultClient. See
+// Client.Synthesize.
+func Synthesize(ctx context.Context, text string, opts Options) (*Audio, error) {
+	return DefaultClient.Synthesize(ctx, text, opts)
 }
 
 // PlayAudio plays the specified audio file
 func PlayAudio(wavName string) error {
-	var cmd *exec.Cmd
-	if runtime.GOOS == "darwin" {
-		cmd = exec.Command("afplay", wavName)
-	} else if runtime.GOOS == "windows" {
-		cmd = exec.Command("cmd", "/c", "start", "", wavName)
-	} else {
-		return fmt.Errorf("unsupported operating system")
-	}
+	return DefaultClient.PlayAudio(wavName)
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("wav file play failed: %v", err)
-	}
-	return nil
+// PlayAudioContext is like PlayAudio but stops playback when ctx is done.
+func PlayAudioContext(ctx context.Context, wavName string) error {
+	return DefaultClient.PlayAudioContext(ctx, wavName)
 }
 
 // ProcessTextFiles processes text files in a directory and generates audio files
-func ProcessTextFiles(dir string, opts Options) error {
-	files, err := ioutil.ReadDir(dir)
-	if err != nil {
-		return fmt.Errorf("error reading directory: %v", err)
-	}
-
-	for _, file := range files {
-		if !file.IsDir() && filepath.Ext(file.Name()) == ".txt" {
-			filePath := filepath.Join(dir, file.Name())
-			content, err := readTextFile(filePath)
-			if err != nil {
-				log.Printf("Error reading file (%s): %v", file.Name(), err)
-				continue
-			}
-
-			outputName := convertWavExt(file.Name())
-			outputPath := filepath.Join(dir, outputName)
-
-			if opts.Output != "" {
-				// Override directory output
-				outputPath = filepath.Join(opts.Output, outputName)
-			}
-
-			localOpts := opts
-			localOpts.Output = outputPath
-
-			if err := GenerateSpeech(content, localOpts); err != nil {
-				log.Printf("Error generating speech for file (%s): %v", file.Name(), err)
-				continue
-			}
-		}
-	}
+func ProcessTextFiles(dir string, opts Options) (*BatchResult, error) {
+	return DefaultClient.ProcessTextFiles(dir, opts)
+}
 
-	return nil
+// ProcessTextFilesContext is like ProcessTextFiles but stops starting new
+// files and returns ctx.Err() when ctx is done.
+func ProcessTextFilesContext(ctx context.Context, dir string, opts Options) (*BatchResult, error) {
+	return DefaultClient.ProcessTextFilesContext(ctx, dir, opts)
 }
 
 // ParseEmotion validates and normalizes an emotion option string.
@@ -202,17 +238,32 @@ func ParseEmotion(s string) (Emotion, error) {
 
 // ListNarrators returns narrator names installed in VOICEPEAK.
 func ListNarrators() ([]string, error) {
-	return voicepeakList("--list-narrator")
+	return DefaultClient.ListNarrators()
+}
+
+// ListNarratorsContext is like ListNarrators but stops VOICEPEAK when ctx is done.
+func ListNarratorsContext(ctx context.Context) ([]string, error) {
+	return DefaultClient.ListNarratorsContext(ctx)
 }
 
 // ListEmotions returns emotion names available for the given narrator.
 func ListEmotions(narrator string) ([]string, error) {
-	narrator = resolveNarratorName(narrator)
-	if narrator == "" {
-		return nil, fmt.Errorf("narrator is required")
-	}
+	return DefaultClient.ListEmotions(narrator)
+}
+
+// ListEmotionsContext is like ListEmotions but stops VOICEPEAK when ctx is done.
+func ListEmotionsContext(ctx context.Context, narrator string) ([]string, error) {
+	return DefaultClient.ListEmotionsContext(ctx, narrator)
+}
+
+// ListVoices returns every installed narrator with its emotions.
+func ListVoices() ([]Voice, error) {
+	return DefaultClient.ListVoices()
+}
 
-	return voicepeakList("--list-emotion", narrator)
+// ListVoicesContext is like ListVoices but stops VOICEPEAK when ctx is done.
+func ListVoicesContext(ctx context.Context) ([]Voice, error) {
+	return DefaultClient.ListVoicesContext(ctx)
 }
 
 // ValidateEmotionExpression validates and normalizes a VOICEPEAK emotion expression.
@@ -310,18 +361,13 @@ func normalizeEmotionExpression(raw string) (string, error) {
 	return strings.Join(parts, ","), nil
 }
 
-func voicepeakList(args ...string) ([]string, error) {
-	cmd := vpCmd(args)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		output = bytes.TrimSpace(output)
-		if len(output) == 0 {
-			return nil, fmt.Errorf("voicepeak command failed: %w", err)
-		}
-		return nil, fmt.Errorf("voicepeak command failed: %w: %s", err, output)
+// commandError attaches the trimmed VOICEPEAK output, if any, to err.
+func commandError(err error, output []byte) error {
+	output = bytes.TrimSpace(output)
+	if len(output) == 0 {
+		return err
 	}
-
-	return parseVoicepeakListOutput(string(output)), nil
+	return fmt.Errorf("%w: %s", err, output)
 }
 
 func parseVoicepeakListOutput(output string) []string {
@@ -336,20 +382,18 @@ func parseVoicepeakListOutput(output string) []string {
 	return items
 }
 
-func resolveNarratorName(narrator string) string {
-	if resolved, ok := narratorMap[narrator]; ok {
-		return resolved
+func vpCmd(ctx context.Context, path string, options []string) (*exec.Cmd, error) {
+	if path == "" {
+		return nil, fmt.Errorf("%w: no default VOICEPEAK path for %s", ErrUnsupportedPlatform, runtime.GOOS)
 	}
-	return narrator
-}
 
-func vpCmd(options []string) *exec.Cmd {
-	_, err := exec.LookPath(VoicepeakPath)
-	if err != nil {
-		log.Fatalf("Command not found: %v", err)
+	if _, err := exec.LookPath(path); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVoicepeakNotFound, err)
 	}
 
-	return exec.Command(VoicepeakPath, options...)
+	cmd := exec.CommandContext(ctx, path, options...)
+	killProcessTreeOnCancel(cmd)
+	return cmd, nil
 }
 
 func convertWavExt(filename string) string {
@@ -357,7 +401,7 @@ func convertWavExt(filename string) string {
 	return strings.TrimSuffix(filename, oldExt) + ".wav"
 }
 
-func buildOptions(text string, opts Options) []string {
+func buildOptions(text string, opts Options) ([]string, error) {
 	options := []string{"-s", text}
 
 	narrator := resolveNarratorName(opts.Narrator)
@@ -368,7 +412,7 @@ func buildOptions(text string, opts Options) []string {
 	if opts.Emotion != "" {
 		emotion, err := normalizeEmotionExpression(opts.Emotion)
 		if err != nil {
-			log.Fatalf("Invalid emotion option: %s", opts.Emotion)
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidEmotion, opts.Emotion, err)
 		}
 		if emotion != "" {
 			options = append([]string{"--emotion", emotion}, options...)
@@ -387,13 +431,5 @@ func buildOptions(text string, opts Options) []string {
 		options = append(options, "--pitch", fmt.Sprintf("%d", *opts.Pitch))
 	}
 
-	return options
-}
-
-func readTextFile(filePath string) (string, error) {
-	content, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		return "", err
-	}
-	return string(content), nil
+	return options, nil
 }
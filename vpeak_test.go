@@ -1,6 +1,9 @@
 package vpeak
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestParseEmotion(t *testing.T) {
 	tests := []struct {
@@ -214,7 +217,10 @@ func TestBuildOptionsEmotion(t *testing.T) {
 	}
 
 	t.Run("bare emotion expands to 100", func(t *testing.T) {
-		opts := buildOptions("hi", Options{Narrator: "f1", Emotion: "happy"})
+		opts, err := buildOptions("hi", Options{Narrator: "f1", Emotion: "happy"})
+		if err != nil {
+			t.Fatalf("buildOptions() error = %v", err)
+		}
 		if v, _ := flagValue(opts, "--narrator"); v != "Japanese Female 1" {
 			t.Fatalf("--narrator = %q, want %q", v, "Japanese Female 1")
 		}
@@ -224,7 +230,10 @@ func TestBuildOptionsEmotion(t *testing.T) {
 	})
 
 	t.Run("dynamic emotion names pass through", func(t *testing.T) {
-		opts := buildOptions("hi", Options{Narrator: "Zundamon", Emotion: "amaama=40,live=60"})
+		opts, err := buildOptions("hi", Options{Narrator: "Zundamon", Emotion: "amaama=40,live=60"})
+		if err != nil {
+			t.Fatalf("buildOptions() error = %v", err)
+		}
 		if v, _ := flagValue(opts, "--narrator"); v != "Zundamon" {
 			t.Fatalf("--narrator = %q, want %q", v, "Zundamon")
 		}
@@ -234,16 +243,28 @@ func TestBuildOptionsEmotion(t *testing.T) {
 	})
 
 	t.Run("all-zero emotion omits --emotion", func(t *testing.T) {
-		opts := buildOptions("hi", Options{Narrator: "f1", Emotion: "happy=0"})
+		opts, err := buildOptions("hi", Options{Narrator: "f1", Emotion: "happy=0"})
+		if err != nil {
+			t.Fatalf("buildOptions() error = %v", err)
+		}
 		if _, ok := flagValue(opts, "--emotion"); ok {
 			t.Fatalf("--emotion should be omitted for all-zero emotion, got %v", opts)
 		}
 	})
 
 	t.Run("no emotion omits --emotion", func(t *testing.T) {
-		opts := buildOptions("hi", Options{Narrator: "f1"})
+		opts, err := buildOptions("hi", Options{Narrator: "f1"})
+		if err != nil {
+			t.Fatalf("buildOptions() error = %v", err)
+		}
 		if _, ok := flagValue(opts, "--emotion"); ok {
 			t.Fatalf("--emotion should be omitted when no emotion given, got %v", opts)
 		}
 	})
+	t.Run("invalid emotion returns ErrInvalidEmotion", func(t *testing.T) {
+		_, err := buildOptions("hi", Options{Narrator: "f1", Emotion: "happy=101"})
+		if !errors.Is(err, ErrInvalidEmotion) {
+			t.Fatalf("buildOptions() error = %v, want ErrInvalidEmotion", err)
+		}
+	})
 }
@@ -0,0 +1,59 @@
+package vpeaktest
+
+// Config controls the fake VOICEPEAK. It is stored as JSON next to the fake
+// executable, so changes apply to the next invocation.
+type Config struct {
+	// Narrators is printed by --list-narrator. Synthesis with a narrator not
+	// listed here fails.
+	Narrators []string `json:"narrators"`
+	// Emotions is printed by --list-emotion for each narrator.
+	Emotions map[string][]string `json:"emotions"`
+
+	// SampleRate of the rendered 16-bit mono sine wave. Zero uses 48000.
+	SampleRate int `json:"sample_rate,omitempty"`
+	// MillisPerChar is the length of rendered audio per character of text.
+	// Zero uses 100.
+	MillisPerChar int `json:"millis_per_char,omitempty"`
+
+	// ExitCode, if non-zero, makes every invocation print Stderr and exit
+	// with this code.
+	ExitCode int    `json:"exit_code,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+	// FailOn makes synthesis of text containing it fail.
+	FailOn string `json:"fail_on,omitempty"`
+	// Hang makes every invocation block until it is killed.
+	Hang bool `json:"hang,omitempty"`
+	// Noise makes every invocation print the debug lines the real VOICEPEAK
+	// writes to stdout.
+	Noise bool `json:"noise,omitempty"`
+
+	// CallLog is the file each invocation's arguments are appended to. It is
+	// set by the harness.
+	CallLog string `json:"call_log"`
+}
+
+// DefaultNarrators are the narrators the fake VOICEPEAK lists by default.
+var DefaultNarrators = []string{
+	"Japanese Female 1",
+	"Japanese Female 2",
+	"Japanese Female 3",
+	"Japanese Male 1",
+	"Japanese Male 2",
+	"Japanese Male 3",
+	"Japanese Female Child",
+}
+
+// DefaultEmotions are the emotions every default narrator lists.
+var DefaultEmotions = []string{"happy", "fun", "angry", "sad"}
+
+// DefaultConfig returns the configuration of a new fake VOICEPEAK.
+func DefaultConfig() Config {
+	emotions := map[string][]string{}
+	for _, narrator := range DefaultNarrators {
+		emotions[narrator] = append([]string(nil), DefaultEmotions...)
+	}
+	return Config{
+		Narrators: append([]string(nil), DefaultNarrators...),
+		Emotions:  emotions,
+	}
+}
@@ -0,0 +1,195 @@
+// Command fakevoicepeak mimics the VOICEPEAK command-line interface for
+// tests. It reads its behavior from a vpeaktest.Config stored as JSON in the
+// executable's path plus ".json". See package vpeaktest.
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shinshin86/vpeak/vpeaktest"
+	"github.com/shinshin86/vpeak/wav"
+)
+
+func main() {
+	config, err := loadConfig()
+	if err != nil {
+		fail(1, err.Error())
+	}
+	if err := logCall(config.CallLog, os.Args[1:]); err != nil {
+		fail(1, err.Error())
+	}
+
+	if config.Noise {
+		fmt.Printf("[debug][%d][voicepeak.GeneralDebug] UserApplication Folder: /tmp/Voicepeak\n", time.Now().Unix())
+		fmt.Println("iconv_open is not supported")
+	}
+	if config.Hang {
+		for {
+			time.Sleep(time.Hour)
+		}
+	}
+	if config.ExitCode != 0 {
+		fail(config.ExitCode, config.Stderr)
+	}
+
+	args := parseArgs(os.Args[1:])
+	switch {
+	case args.listNarrators:
+		for _, narrator := range config.Narrators {
+			fmt.Println(narrator)
+		}
+	case args.listEmotions != "":
+		emotions, ok := config.Emotions[args.listEmotions]
+		if !ok {
+			fail(1, "narrator not found: "+args.listEmotions)
+		}
+		for _, emotion := range emotions {
+			fmt.Println(emotion)
+		}
+	default:
+		synthesize(config, args)
+	}
+}
+
+type arguments struct {
+	listNarrators bool
+	listEmotions  string
+	narrator      string
+	text          string
+	output        string
+}
+
+func parseArgs(argv []string) arguments {
+	args := arguments{output: "output.wav"}
+	value := func(i int) string {
+		if i+1 >= len(argv) {
+			fail(1, "missing value for "+argv[i])
+		}
+		return argv[i+1]
+	}
+
+	for i := 0; i < len(argv); i++ {
+		switch argv[i] {
+		case "--list-narrator":
+			args.listNarrators = true
+		case "--list-emotion":
+			args.listEmotions = value(i)
+			i++
+		case "--narrator", "-n":
+			args.narrator = value(i)
+			i++
+		case "-s", "--say":
+			args.text = value(i)
+			i++
+		case "-t", "--text":
+			data, err := os.ReadFile(value(i))
+			if err != nil {
+				fail(1, err.Error())
+			}
+			args.text = string(data)
+			i++
+		case "-o", "--out":
+			args.output = value(i)
+			i++
+		case "-e", "--emotion", "--speed", "--pitch":
+			value(i)
+			i++
+		default:
+			fail(1, "unknown option: "+argv[i])
+		}
+	}
+	return args
+}
+
+func synthesize(config vpeaktest.Config, args arguments) {
+	if args.text == "" {
+		fail(1, "no text to synthesize")
+	}
+	if args.narrator != "" && !contains(config.Narrators, args.narrator) {
+		fail(1, "narrator not found: "+args.narrator)
+	}
+	if config.FailOn != "" && strings.Contains(args.text, config.FailOn) {
+		fail(1, "synthesis failed")
+	}
+
+	sampleRate := config.SampleRate
+	if sampleRate == 0 {
+		sampleRate = 48000
+	}
+	millisPerChar := config.MillisPerChar
+	if millisPerChar == 0 {
+		millisPerChar = 100
+	}
+
+	frames := sampleRate * millisPerChar * utf8.RuneCountInString(args.text) / 1000
+	data := make([]byte, frames*2)
+	for i := 0; i < frames; i++ {
+		sample := int16(8000 * math.Sin(2*math.Pi*440*float64(i)/float64(sampleRate)))
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(sample))
+	}
+
+	audio := &wav.Audio{
+		Format: wav.Format{AudioFormat: wav.FormatPCM, Channels: 1, SampleRate: uint32(sampleRate), BitsPerSample: 16},
+		Data:   data,
+	}
+	if err := audio.WriteFile(args.output); err != nil {
+		fail(1, err.Error())
+	}
+}
+
+func loadConfig() (vpeaktest.Config, error) {
+	var config vpeaktest.Config
+	executable, err := os.Executable()
+	if err != nil {
+		return config, err
+	}
+
+	data, err := os.ReadFile(executable + ".json")
+	if err != nil {
+		return config, err
+	}
+	return config, json.Unmarshal(data, &config)
+}
+
+func logCall(path string, args []string) error {
+	if path == "" {
+		return nil
+	}
+
+	line, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func contains(items []string, item string) bool {
+	for _, candidate := range items {
+		if candidate == item {
+			return true
+		}
+	}
+	return false
+}
+
+func fail(code int, message string) {
+	if message != "" {
+		fmt.Fprintln(os.Stderr, message)
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,265 @@
+// Package vpeaktest provides a fake VOICEPEAK executable for hermetic tests
+// of code that uses vpeak.
+//
+// The fake is compiled from the fakevoicepeak command with the go tool the
+// first time it is needed. It records every invocation's arguments, renders
+// a deterministic sine wave to the -o path, answers --list-narrator and
+// --list-emotion from a Config, and can simulate failures, hangs and the
+// debug output of the real application.
+//
+//	fake := vpeaktest.New(t)
+//	client := fake.Client()
+//	if err := client.GenerateSpeech("こんにちは", vpeak.Options{Output: out, Silent: true}); err != nil {
+//		t.Fatal(err)
+//	}
+//	calls := fake.Calls()
+//
+// The compiled fake is shared by the tests of a package. Run them with Main
+// so that it is removed afterwards:
+//
+//	func TestMain(m *testing.M) {
+//		vpeaktest.Main(m)
+//	}
+package vpeaktest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/shinshin86/vpeak"
+)
+
+const fakePackage = "github.com/shinshin86/vpeak/vpeaktest/fakevoicepeak"
+
+var (
+	buildMu   sync.Mutex
+	buildDir  string
+	buildPath string
+	buildErr  error
+)
+
+// Voicepeak is a fake VOICEPEAK installation owned by one test.
+type Voicepeak struct {
+	// Path is the fake executable.
+	Path string
+
+	t      testing.TB
+	mu     sync.Mutex
+	config Config
+}
+
+// New installs a fake VOICEPEAK with DefaultConfig in a temporary directory
+// that is removed when the test ends.
+func New(t testing.TB) *Voicepeak {
+	t.Helper()
+
+	binary, err := build()
+	if err != nil {
+		t.Fatalf("vpeaktest: build fake voicepeak: %v", err)
+	}
+
+	dir := t.TempDir()
+	name := "voicepeak"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	v := &Voicepeak{Path: filepath.Join(dir, name), t: t}
+	if err := copyExecutable(binary, v.Path); err != nil {
+		t.Fatalf("vpeaktest: install fake voicepeak: %v", err)
+	}
+
+	config := DefaultConfig()
+	config.CallLog = filepath.Join(dir, "calls.jsonl")
+	v.SetConfig(config)
+	return v
+}
+
+// build compiles the fake once per process, or again after Cleanup.
+func build() (string, error) {
+	buildMu.Lock()
+	defer buildMu.Unlock()
+
+	if buildPath != "" || buildErr != nil {
+		return buildPath, buildErr
+	}
+
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		goTool = filepath.Join(runtime.GOROOT(), "bin", "go")
+	}
+
+	dir, err := os.MkdirTemp("", "vpeaktest-")
+	if err != nil {
+		buildErr = err
+		return "", buildErr
+	}
+	path := filepath.Join(dir, "voicepeak")
+
+	output, err := exec.Command(goTool, "build", "-o", path, fakePackage).CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		buildErr = fmt.Errorf("%v: %s", err, output)
+		return "", buildErr
+	}
+	buildDir, buildPath = dir, path
+	return buildPath, nil
+}
+
+// Cleanup removes the fake compiled for this process. Installed fakes are
+// copies and keep working until their tests end.
+func Cleanup() error {
+	buildMu.Lock()
+	defer buildMu.Unlock()
+
+	dir := buildDir
+	buildDir, buildPath, buildErr = "", "", nil
+	if dir == "" {
+		return nil
+	}
+	return os.RemoveAll(dir)
+}
+
+// Main runs the tests of m, removes the compiled fake and exits with the
+// result. Call it from TestMain.
+func Main(m *testing.M) {
+	code := m.Run()
+	if err := Cleanup(); err != nil {
+		fmt.Fprintf(os.Stderr, "vpeaktest: %v\n", err)
+	}
+	os.Exit(code)
+}
+
+// Client returns a client that runs the fake.
+func (v *Voicepeak) Client() *vpeak.Client {
+	return vpeak.NewClient(v.Path)
+}
+
+// Configure points c at the fake.
+func (v *Voicepeak) Configure(c *vpeak.Client) {
+	c.Path = v.Path
+	c.Engine = nil
+	c.Wine = nil
+}
+
+// Config returns the current configuration.
+func (v *Voicepeak) Config() Config {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.config
+}
+
+// SetConfig replaces the configuration used by later invocations. The call
+// log is kept unless config sets its own.
+func (v *Voicepeak) SetConfig(config Config) {
+	v.t.Helper()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if config.CallLog == "" {
+		config.CallLog = v.config.CallLog
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		v.t.Fatalf("vpeaktest: encode config: %v", err)
+	}
+	if err := os.WriteFile(v.Path+".json", data, 0o644); err != nil {
+		v.t.Fatalf("vpeaktest: write config: %v", err)
+	}
+	v.config = config
+}
+
+// Update changes the configuration with fn.
+func (v *Voicepeak) Update(fn func(*Config)) {
+	v.t.Helper()
+	config := v.Config()
+	fn(&config)
+	v.SetConfig(config)
+}
+
+// Fail makes every invocation print stderr and exit with code.
+func (v *Voicepeak) Fail(code int, stderr string) {
+	v.t.Helper()
+	v.Update(func(c *Config) { c.ExitCode, c.Stderr = code, stderr })
+}
+
+// FailOn makes synthesis of text containing substr fail.
+func (v *Voicepeak) FailOn(substr string) {
+	v.t.Helper()
+	v.Update(func(c *Config) { c.FailOn = substr })
+}
+
+// Hang makes every invocation block until it is killed.
+func (v *Voicepeak) Hang() {
+	v.t.Helper()
+	v.Update(func(c *Config) { c.Hang = true })
+}
+
+// Noise makes every invocation print VOICEPEAK's debug lines.
+func (v *Voicepeak) Noise() {
+	v.t.Helper()
+	v.Update(func(c *Config) { c.Noise = true })
+}
+
+// Calls returns the arguments of every invocation so far, in order.
+func (v *Voicepeak) Calls() [][]string {
+	v.t.Helper()
+
+	file, err := os.Open(v.Config().CallLog)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		v.t.Fatalf("vpeaktest: read call log: %v", err)
+	}
+	defer file.Close()
+
+	var calls [][]string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var args []string
+		if err := json.Unmarshal(scanner.Bytes(), &args); err != nil {
+			v.t.Fatalf("vpeaktest: parse call log: %v", err)
+		}
+		calls = append(calls, args)
+	}
+	if err := scanner.Err(); err != nil {
+		v.t.Fatalf("vpeaktest: read call log: %v", err)
+	}
+	return calls
+}
+
+// Reset forgets the recorded calls.
+func (v *Voicepeak) Reset() {
+	v.t.Helper()
+	if err := os.Remove(v.Config().CallLog); err != nil && !errors.Is(err, os.ErrNotExist) {
+		v.t.Fatalf("vpeaktest: reset call log: %v", err)
+	}
+}
+
+func copyExecutable(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,129 @@
+package vpeaktest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shinshin86/vpeak"
+	"github.com/shinshin86/vpeak/wav"
+)
+
+func TestMain(m *testing.M) {
+	Main(m)
+}
+
+func TestCleanup(t *testing.T) {
+	path, err := build()
+	if err != nil {
+		t.Fatalf("build() error = %v", err)
+	}
+	if err := Cleanup(); err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Dir(path)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("build directory after Cleanup: %v, want it removed", err)
+	}
+
+	// Later tests build the fake again.
+	if _, err := build(); err != nil {
+		t.Fatalf("build() after Cleanup error = %v", err)
+	}
+}
+
+func TestFakeSynthesizes(t *testing.T) {
+	fake := New(t)
+	client := fake.Client()
+
+	output := filepath.Join(t.TempDir(), "out.wav")
+	if err := client.GenerateSpeech("こんにちは", vpeak.Options{Narrator: "f1", Output: output, Silent: true}); err != nil {
+		t.Fatalf("GenerateSpeech() error = %v", err)
+	}
+
+	clip, err := wav.ReadFile(output)
+	if err != nil {
+		t.Fatalf("wav.ReadFile() error = %v", err)
+	}
+	if clip.Format.SampleRate != 48000 || clip.Duration() != 500*time.Millisecond {
+		t.Fatalf("rendered %d Hz, %v, want 48000 Hz, 500ms", clip.Format.SampleRate, clip.Duration())
+	}
+
+	want := [][]string{{"-o", output, "--narrator", "Japanese Female 1", "-s", "こんにちは"}}
+	if got := fake.Calls(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Calls() = %q, want %q", got, want)
+	}
+
+	fake.Reset()
+	if got := fake.Calls(); len(got) != 0 {
+		t.Fatalf("Calls() after Reset = %q, want none", got)
+	}
+}
+
+func TestFakeLists(t *testing.T) {
+	fake := New(t)
+	fake.Noise()
+	fake.Update(func(c *Config) {
+		c.Narrators = []string{"Zundamon"}
+		c.Emotions = map[string][]string{"Zundamon": {"amaama", "live"}}
+	})
+	client := fake.Client()
+
+	narrators, err := client.ListNarrators()
+	if err != nil {
+		t.Fatalf("ListNarrators() error = %v", err)
+	}
+	if want := []string{"Zundamon"}; !reflect.DeepEqual(narrators, want) {
+		t.Fatalf("ListNarrators() = %q, want %q", narrators, want)
+	}
+
+	emotions, err := client.ListEmotions("Zundamon")
+	if err != nil {
+		t.Fatalf("ListEmotions() error = %v", err)
+	}
+	if want := []string{"amaama", "live"}; !reflect.DeepEqual(emotions, want) {
+		t.Fatalf("ListEmotions() = %q, want %q", emotions, want)
+	}
+
+	if _, err := client.ListEmotions("Nobody"); err == nil {
+		t.Fatal("ListEmotions(unknown) error = nil, want failure")
+	}
+}
+
+func TestFakeFailures(t *testing.T) {
+	fake := New(t)
+	client := fake.Client()
+	output := filepath.Join(t.TempDir(), "out.wav")
+
+	fake.FailOn("だめ")
+	if err := client.GenerateSpeech("だめです", vpeak.Options{Output: output, Silent: true}); err == nil {
+		t.Fatal("GenerateSpeech() error = nil, want FailOn failure")
+	}
+	if err := client.GenerateSpeech("いいです", vpeak.Options{Output: output, Silent: true}); err != nil {
+		t.Fatalf("GenerateSpeech() error = %v", err)
+	}
+
+	fake.Fail(3, "license expired")
+	err := client.GenerateSpeech("いいです", vpeak.Options{Output: output, Silent: true})
+	if err == nil || !strings.Contains(err.Error(), "license expired") {
+		t.Fatalf("GenerateSpeech() error = %v, want stderr in error", err)
+	}
+}
+
+func TestFakeHang(t *testing.T) {
+	fake := New(t)
+	fake.Hang()
+	client := fake.Client()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	err := client.GenerateSpeechContext(ctx, "hi", vpeak.Options{Output: filepath.Join(t.TempDir(), "out.wav"), Silent: true})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GenerateSpeechContext() error = %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,377 @@
+// Package wav reads, edits and writes PCM WAV files such as the ones rendered
+// by VOICEPEAK.
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+const (
+	// FormatPCM is the WAVE format tag for integer PCM samples.
+	FormatPCM = 1
+	// FormatIEEEFloat is the WAVE format tag for floating point samples.
+	FormatIEEEFloat = 3
+	// FormatExtensible is the WAVE format tag for WAVE_FORMAT_EXTENSIBLE.
+	FormatExtensible = 0xFFFE
+)
+
+var (
+	ErrInvalidWAV     = errors.New("invalid WAV data")
+	ErrFormatMismatch = errors.New("WAV formats differ")
+)
+
+// Format describes how samples are encoded.
+type Format struct {
+	AudioFormat   uint16
+	Channels      uint16
+	SampleRate    uint32
+	BitsPerSample uint16
+	// Extra holds the fmt chunk bytes after the basic 16 byte header,
+	// including the extension size, so that extensible formats round-trip.
+	Extra []byte
+}
+
+// BlockAlign returns the size in bytes of one frame (one sample per channel).
+func (f Format) BlockAlign() int {
+	return int(f.Channels) * int(f.BitsPerSample) / 8
+}
+
+// ByteRate returns the number of bytes per second of audio.
+func (f Format) ByteRate() int {
+	return int(f.SampleRate) * f.BlockAlign()
+}
+
+// Equal reports whether f and other encode samples the same way.
+func (f Format) Equal(other Format) bool {
+	return f.AudioFormat == other.AudioFormat &&
+		f.Channels == other.Channels &&
+		f.SampleRate == other.SampleRate &&
+		f.BitsPerSample == other.BitsPerSample &&
+		bytes.Equal(f.Extra, other.Extra)
+}
+
+func (f Format) String() string {
+	return fmt.Sprintf("%d Hz, %d ch, %d bit", f.SampleRate, f.Channels, f.BitsPerSample)
+}
+
+// Audio is a decoded WAV file: its format and raw interleaved sample data.
+type Audio struct {
+	Format Format
+	Data   []byte
+}
+
+// Read parses a RIFF/WAVE stream. Chunks other than "fmt " and "data" are
+// skipped.
+func Read(r io.Reader) (*Audio, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(raw)
+}
+
+// ReadFile reads and parses the WAV file at path.
+func ReadFile(path string) (*Audio, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	audio, err := Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return audio, nil
+}
+
+// Parse decodes WAV file contents. The returned Audio shares memory with raw.
+func Parse(raw []byte) (*Audio, error) {
+	if len(raw) < 12 || string(raw[0:4]) != "RIFF" || string(raw[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("%w: missing RIFF/WAVE header", ErrInvalidWAV)
+	}
+
+	var audio Audio
+	var hasFormat, hasData bool
+	for offset := 12; offset+8 <= len(raw); {
+		id := string(raw[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(raw[offset+4 : offset+8]))
+		body := raw[offset+8:]
+		if size > len(body) {
+			// Tolerate streams whose data size was never patched.
+			size = len(body)
+		}
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, fmt.Errorf("%w: fmt chunk too short", ErrInvalidWAV)
+			}
+			audio.Format = Format{
+				AudioFormat:   binary.LittleEndian.Uint16(body[0:2]),
+				Channels:      binary.LittleEndian.Uint16(body[2:4]),
+				SampleRate:    binary.LittleEndian.Uint32(body[4:8]),
+				BitsPerSample: binary.LittleEndian.Uint16(body[14:16]),
+			}
+			if size > 16 {
+				audio.Format.Extra = body[16:size]
+			}
+			hasFormat = true
+		case "data":
+			audio.Data = body[:size]
+			hasData = true
+		}
+		offset += 8 + size + size%2
+	}
+
+	if !hasFormat {
+		return nil, fmt.Errorf("%w: missing fmt chunk", ErrInvalidWAV)
+	}
+	if !hasData {
+		return nil, fmt.Errorf("%w: missing data chunk", ErrInvalidWAV)
+	}
+	if audio.Format.Channels == 0 || audio.Format.SampleRate == 0 || audio.Format.BlockAlign() == 0 {
+		return nil, fmt.Errorf("%w: unsupported format %s", ErrInvalidWAV, audio.Format)
+	}
+
+	audio.Data = audio.Data[:len(audio.Data)-len(audio.Data)%audio.Format.BlockAlign()]
+	return &audio, nil
+}
+
+// Frames returns the number of sample frames in a.
+func (a *Audio) Frames() int {
+	return len(a.Data) / a.Format.BlockAlign()
+}
+
+// Duration returns the playing time of a.
+func (a *Audio) Duration() time.Duration {
+	return time.Duration(a.Frames()) * time.Second / time.Duration(a.Format.SampleRate)
+}
+
+// Bytes encodes a as a WAV file.
+func (a *Audio) Bytes() []byte {
+	var buf bytes.Buffer
+	a.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+// WriteTo writes a to w as a WAV file.
+func (a *Audio) WriteTo(w io.Writer) (int64, error) {
+	f := a.Format
+	fmtSize := 16 + len(f.Extra)
+	dataPad := len(a.Data) % 2
+	fmtPad := fmtSize % 2
+
+	var header bytes.Buffer
+	header.WriteString("RIFF")
+	binary.Write(&header, binary.LittleEndian, uint32(4+8+fmtSize+fmtPad+8+len(a.Data)+dataPad))
+	header.WriteString("WAVE")
+	header.WriteString("fmt ")
+	binary.Write(&header, binary.LittleEndian, uint32(fmtSize))
+	binary.Write(&header, binary.LittleEndian, f.AudioFormat)
+	binary.Write(&header, binary.LittleEndian, f.Channels)
+	binary.Write(&header, binary.LittleEndian, f.SampleRate)
+	binary.Write(&header, binary.LittleEndian, uint32(f.ByteRate()))
+	binary.Write(&header, binary.LittleEndian, uint16(f.BlockAlign()))
+	binary.Write(&header, binary.LittleEndian, f.BitsPerSample)
+	header.Write(f.Extra)
+	if fmtPad == 1 {
+		header.WriteByte(0)
+	}
+	header.WriteString("data")
+	binary.Write(&header, binary.LittleEndian, uint32(len(a.Data)))
+
+	var written int64
+	for _, part := range [][]byte{header.Bytes(), a.Data, make([]byte, dataPad)} {
+		n, err := w.Write(part)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// WriteFile writes a to path as a WAV file.
+func (a *Audio) WriteFile(path string) error {
+	return os.WriteFile(path, a.Bytes(), 0o644)
+}
+
+// Silence returns d worth of silent audio in format f, which is empty if d
+// is not positive.
+func Silence(f Format, d time.Duration) *Audio {
+	if d <= 0 {
+		return &Audio{Format: f}
+	}
+	frames := int(d * time.Duration(f.SampleRate) / time.Second)
+	fill := byte(0)
+	if f.AudioFormat != FormatIEEEFloat && f.BitsPerSample == 8 {
+		// 8-bit PCM is unsigned with its midpoint at 0x80.
+		fill = 0x80
+	}
+	return &Audio{Format: f, Data: bytes.Repeat([]byte{fill}, frames*f.BlockAlign())}
+}
+
+// Concat joins clips in order, inserting gap of silence between neighbours.
+// All clips must share the same format; otherwise ErrFormatMismatch is
+// returned.
+func Concat(clips []*Audio, gap time.Duration) (*Audio, error) {
+	if len(clips) == 0 {
+		return nil, fmt.Errorf("%w: no clips to concatenate", ErrInvalidWAV)
+	}
+
+	format := clips[0].Format
+	var silence []byte
+	if gap > 0 {
+		silence = Silence(format, gap).Data
+	}
+
+	var data bytes.Buffer
+	for i, clip := range clips {
+		if !clip.Format.Equal(format) {
+			return nil, fmt.Errorf("%w: clip %d is %s, want %s", ErrFormatMismatch, i, clip.Format, format)
+		}
+		if i > 0 {
+			data.Write(silence)
+		}
+		data.Write(clip.Data)
+	}
+
+	return &Audio{Format: format, Data: data.Bytes()}, nil
+}
+
+// Trim returns the part of a between start and end. Bounds are clamped to the
+// clip, and an end of zero means the end of the clip. The result shares
+// sample data with a.
+func (a *Audio) Trim(start, end time.Duration) *Audio {
+	frames := a.Frames()
+	first := clampFrame(a.frameAt(start), frames)
+	last := frames
+	if end > 0 {
+		last = clampFrame(a.frameAt(end), frames)
+	}
+	if last < first {
+		last = first
+	}
+
+	blockAlign := a.Format.BlockAlign()
+	return &Audio{Format: a.Format, Data: a.Data[first*blockAlign : last*blockAlign]}
+}
+
+func (a *Audio) frameAt(d time.Duration) int {
+	return int(d * time.Duration(a.Format.SampleRate) / time.Second)
+}
+
+func clampFrame(frame, frames int) int {
+	if frame < 0 {
+		return 0
+	}
+	if frame > frames {
+		return frames
+	}
+	return frame
+}
+
+// Convert returns a as 16-bit PCM with the given number of channels and
+// sample rate. Channels are mixed down to mono or mono is copied to every
+// channel, and the sample rate is changed by linear interpolation.
+func (a *Audio) Convert(channels uint16, sampleRate uint32) (*Audio, error) {
+	src := a.Format
+	if channels == 0 || sampleRate == 0 {
+		return nil, fmt.Errorf("%w: invalid target format", ErrFormatMismatch)
+	}
+	if channels != src.Channels && channels != 1 && src.Channels != 1 {
+		return nil, fmt.Errorf("%w: cannot convert %d to %d channels", ErrFormatMismatch, src.Channels, channels)
+	}
+
+	samples, err := a.samples()
+	if err != nil {
+		return nil, err
+	}
+
+	frames := a.Frames()
+	outFrames := int(int64(frames) * int64(sampleRate) / int64(src.SampleRate))
+	format := Format{AudioFormat: FormatPCM, Channels: channels, SampleRate: sampleRate, BitsPerSample: 16}
+	data := make([]byte, 0, outFrames*format.BlockAlign())
+
+	// sample returns channel ch of frame i in the target channel layout.
+	sample := func(i, ch int) float64 {
+		row := samples[i*int(src.Channels) : (i+1)*int(src.Channels)]
+		switch {
+		case int(channels) == len(row):
+			return row[ch]
+		case channels == 1:
+			var sum float64
+			for _, v := range row {
+				sum += v
+			}
+			return sum / float64(len(row))
+		default:
+			return row[0]
+		}
+	}
+
+	for i := 0; i < outFrames; i++ {
+		pos := float64(i) * float64(src.SampleRate) / float64(sampleRate)
+		first := int(pos)
+		next := first + 1
+		if next >= frames {
+			next = frames - 1
+		}
+		frac := pos - float64(first)
+		for ch := 0; ch < int(channels); ch++ {
+			v := sample(first, ch)*(1-frac) + sample(next, ch)*frac
+			clamped := math.Max(math.MinInt16, math.Min(math.MaxInt16, math.Round(v*(1<<15))))
+			data = binary.LittleEndian.AppendUint16(data, uint16(int16(clamped)))
+		}
+	}
+
+	return &Audio{Format: format, Data: data}, nil
+}
+
+// samples decodes every sample of a into the range [-1, 1].
+func (a *Audio) samples() ([]float64, error) {
+	f := a.Format
+	tag := f.AudioFormat
+	if tag == FormatExtensible && len(f.Extra) >= 10 {
+		// The sub format GUID starts with the actual format tag.
+		tag = binary.LittleEndian.Uint16(f.Extra[8:10])
+	}
+
+	switch {
+	case tag == FormatPCM && (f.BitsPerSample == 8 || f.BitsPerSample == 16 || f.BitsPerSample == 24 || f.BitsPerSample == 32):
+	case tag == FormatIEEEFloat && (f.BitsPerSample == 32 || f.BitsPerSample == 64):
+	default:
+		return nil, fmt.Errorf("%w: cannot decode %s samples", ErrFormatMismatch, f)
+	}
+
+	width := int(f.BitsPerSample) / 8
+	samples := make([]float64, 0, len(a.Data)/width)
+	for i := 0; i+width <= len(a.Data); i += width {
+		b := a.Data[i : i+width]
+		switch {
+		case tag == FormatIEEEFloat && width == 4:
+			samples = append(samples, float64(math.Float32frombits(binary.LittleEndian.Uint32(b))))
+		case tag == FormatIEEEFloat && width == 8:
+			samples = append(samples, math.Float64frombits(binary.LittleEndian.Uint64(b)))
+		case tag == FormatPCM && width == 1:
+			samples = append(samples, (float64(b[0])-128)/128)
+		case tag == FormatPCM && width == 2:
+			samples = append(samples, float64(int16(binary.LittleEndian.Uint16(b)))/(1<<15))
+		case tag == FormatPCM && width == 3:
+			v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+			samples = append(samples, float64(v)/(1<<23))
+		case tag == FormatPCM && width == 4:
+			samples = append(samples, float64(int32(binary.LittleEndian.Uint32(b)))/(1<<31))
+		default:
+			return nil, fmt.Errorf("%w: cannot decode %s samples", ErrFormatMismatch, f)
+		}
+	}
+	return samples, nil
+}
@@ -0,0 +1,200 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func pcm16(sampleRate uint32, channels uint16) Format {
+	return Format{AudioFormat: FormatPCM, Channels: channels, SampleRate: sampleRate, BitsPerSample: 16}
+}
+
+func TestWriteAndParseRoundTrip(t *testing.T) {
+	audio := &Audio{Format: pcm16(48000, 1), Data: bytes.Repeat([]byte{1, 2}, 4800)}
+
+	parsed, err := Parse(audio.Bytes())
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if !parsed.Format.Equal(audio.Format) {
+		t.Fatalf("Format = %+v, want %+v", parsed.Format, audio.Format)
+	}
+	if !bytes.Equal(parsed.Data, audio.Data) {
+		t.Fatal("Data differs after round trip")
+	}
+	if got := parsed.Duration(); got != 100*time.Millisecond {
+		t.Fatalf("Duration() = %v, want 100ms", got)
+	}
+}
+
+func TestParseSkipsUnknownChunks(t *testing.T) {
+	audio := &Audio{Format: pcm16(8000, 2), Data: make([]byte, 40)}
+	raw := audio.Bytes()
+
+	// Insert an odd-sized LIST chunk (with pad byte) before "fmt ".
+	var withList bytes.Buffer
+	withList.Write(raw[:12])
+	withList.WriteString("LIST")
+	binary.Write(&withList, binary.LittleEndian, uint32(3))
+	withList.Write([]byte{'a', 'b', 'c', 0})
+	withList.Write(raw[12:])
+
+	parsed, err := Parse(withList.Bytes())
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if parsed.Format.Channels != 2 || parsed.Frames() != 10 {
+		t.Fatalf("parsed = %s with %d frames, want 2 ch with 10 frames", parsed.Format, parsed.Frames())
+	}
+}
+
+func TestParseRejectsInvalidData(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []byte
+	}{
+		{"empty", nil},
+		{"not riff", []byte("RIFX\x00\x00\x00\x00WAVE")},
+		{"missing chunks", []byte("RIFF\x04\x00\x00\x00WAVE")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.raw); !errors.Is(err, ErrInvalidWAV) {
+				t.Fatalf("Parse() error = %v, want ErrInvalidWAV", err)
+			}
+		})
+	}
+}
+
+func TestConcatInsertsSilence(t *testing.T) {
+	format := pcm16(1000, 1)
+	a := &Audio{Format: format, Data: bytes.Repeat([]byte{1, 0}, 10)}
+	b := &Audio{Format: format, Data: bytes.Repeat([]byte{2, 0}, 5)}
+
+	joined, err := Concat([]*Audio{a, b}, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Concat() error = %v", err)
+	}
+
+	if got := joined.Frames(); got != 10+20+5 {
+		t.Fatalf("Frames() = %d, want 35", got)
+	}
+	if !bytes.Equal(joined.Data[20:60], make([]byte, 40)) {
+		t.Fatal("gap is not silent")
+	}
+}
+
+func TestConcatRejectsMismatchedFormats(t *testing.T) {
+	a := &Audio{Format: pcm16(48000, 1)}
+	b := &Audio{Format: pcm16(44100, 1)}
+
+	if _, err := Concat([]*Audio{a, b}, 0); !errors.Is(err, ErrFormatMismatch) {
+		t.Fatalf("Concat() error = %v, want ErrFormatMismatch", err)
+	}
+}
+
+func TestSilence8Bit(t *testing.T) {
+	format := Format{AudioFormat: FormatPCM, Channels: 1, SampleRate: 100, BitsPerSample: 8}
+
+	silence := Silence(format, 50*time.Millisecond)
+	if !bytes.Equal(silence.Data, bytes.Repeat([]byte{0x80}, 5)) {
+		t.Fatalf("Silence() data = %v, want five 0x80 bytes", silence.Data)
+	}
+}
+
+func TestSilenceNegative(t *testing.T) {
+	if silence := Silence(pcm16(100, 1), -time.Second); len(silence.Data) != 0 {
+		t.Fatalf("Silence(-1s) data = %v, want none", silence.Data)
+	}
+}
+
+func TestTrim(t *testing.T) {
+	audio := &Audio{Format: pcm16(100, 1), Data: make([]byte, 200)}
+
+	tests := []struct {
+		name       string
+		start, end time.Duration
+		wantFrames int
+	}{
+		{"middle", 100 * time.Millisecond, 300 * time.Millisecond, 20},
+		{"to end", 500 * time.Millisecond, 0, 50},
+		{"clamped", -time.Second, 5 * time.Second, 100},
+		{"inverted", 800 * time.Millisecond, 100 * time.Millisecond, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := audio.Trim(tt.start, tt.end).Frames(); got != tt.wantFrames {
+				t.Fatalf("Trim(%v, %v).Frames() = %d, want %d", tt.start, tt.end, got, tt.wantFrames)
+			}
+		})
+	}
+}
+
+func TestWriteFileAndReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.wav")
+	audio := &Audio{Format: pcm16(22050, 1), Data: []byte{1, 2, 3, 4}}
+
+	if err := audio.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	read, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if read.Format.SampleRate != 22050 || !bytes.Equal(read.Data, audio.Data) {
+		t.Fatalf("ReadFile() = %s %v, want 22050 Hz %v", read.Format, read.Data, audio.Data)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	// a stereo 8 kHz ramp whose channels average to 0.5
+	stereo := &Audio{Format: pcm16(8000, 2)}
+	for i := 0; i < 800; i++ {
+		stereo.Data = binary.LittleEndian.AppendUint16(stereo.Data, uint16(int16(1<<14)))
+		stereo.Data = binary.LittleEndian.AppendUint16(stereo.Data, uint16(int16(3<<13)))
+	}
+
+	mono, err := stereo.Convert(1, 24000)
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	if !mono.Format.Equal(pcm16(24000, 1)) {
+		t.Fatalf("Format = %v, want 24000 Hz mono 16 bit", mono.Format)
+	}
+	if mono.Frames() != 2400 || mono.Duration() != 100*time.Millisecond {
+		t.Fatalf("Frames() = %d, Duration() = %v, want 2400 frames of 100ms", mono.Frames(), mono.Duration())
+	}
+	if got := int16(binary.LittleEndian.Uint16(mono.Data[100:])); got != 5<<12 {
+		t.Fatalf("sample = %d, want the channel average %d", got, 5<<12)
+	}
+
+	float := &Audio{Format: Format{AudioFormat: FormatIEEEFloat, Channels: 1, SampleRate: 48000, BitsPerSample: 32}}
+	float.Data = binary.LittleEndian.AppendUint32(nil, math.Float32bits(-1))
+	float.Data = binary.LittleEndian.AppendUint32(float.Data, math.Float32bits(1))
+	converted, err := float.Convert(1, 48000)
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	if got := []int16{int16(binary.LittleEndian.Uint16(converted.Data)), int16(binary.LittleEndian.Uint16(converted.Data[2:]))}; got[0] != math.MinInt16 || got[1] != math.MaxInt16 {
+		t.Fatalf("samples = %v, want full scale", got)
+	}
+
+	if _, err := (&Audio{Format: pcm16(8000, 2)}).Convert(3, 8000); !errors.Is(err, ErrFormatMismatch) {
+		t.Fatalf("Convert(3 channels) error = %v, want %v", err, ErrFormatMismatch)
+	}
+
+	// 4-bit IMA ADPCM has no whole bytes per sample.
+	adpcm := &Audio{Format: Format{AudioFormat: 0x11, Channels: 2, SampleRate: 8000, BitsPerSample: 4}, Data: make([]byte, 16)}
+	if _, err := adpcm.Convert(1, 8000); !errors.Is(err, ErrFormatMismatch) {
+		t.Fatalf("Convert(ADPCM) error = %v, want %v", err, ErrFormatMismatch)
+	}
+}